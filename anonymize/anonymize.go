@@ -0,0 +1,220 @@
+package anonymize
+
+import (
+	"fmt"
+	"regexp"
+	"reviewer-bot/lexer"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Kinds of renamed values, used as token prefixes
+const (
+	kindFunction = "fn"
+	kindType     = "Type"
+	kindVariable = "var"
+	kindString   = "str"
+)
+
+// tokenPattern matches the neutral tokens produced by a Mapping
+var tokenPattern = regexp.MustCompile(`\b(?:fn|Type|var|str)\d+\b`)
+
+// Mapping renames identifiers and string literals to neutral tokens such as
+// fn1, Type2, var3 and "str4", and maps them back in review text. It is safe
+// for concurrent use; every name keeps its token for the lifetime of the mapping.
+type Mapping struct {
+	mu       sync.Mutex
+	forward  map[string]string
+	reverse  map[string]string
+	counters map[string]int
+}
+
+// New creates an empty mapping
+func New() *Mapping {
+	return &Mapping{
+		forward:  make(map[string]string),
+		reverse:  make(map[string]string),
+		counters: make(map[string]int),
+	}
+}
+
+// Name returns the token for a function name
+func (m *Mapping) Name(name string) string {
+	if m == nil {
+		return name
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.token(name, kindFunction)
+}
+
+// Code renames the identifiers and string literals in code and drops its comments,
+// keeping the language's keywords and common built-ins
+func (m *Mapping) Code(code, language string) string {
+	if m == nil {
+		return code
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out strings.Builder
+	segments := lexer.Split(code, language)
+	for i, segment := range segments {
+		switch segment.Kind {
+		case lexer.Comment:
+			// Comments are dropped, they often name what the code is for
+		case lexer.String:
+			m.writeString(&out, segment.Text)
+		default:
+			nextIsString := i+1 < len(segments) && segments[i+1].Kind == lexer.String
+			m.writeCode(&out, segment.Text, nextIsString)
+		}
+	}
+	return out.String()
+}
+
+// writeString writes a string literal with its content replaced by a token,
+// keeping any prefix and quotes; the caller must hold m.mu
+func (m *Mapping) writeString(out *strings.Builder, literal string) {
+	quote := literal[:1]
+	if strings.HasPrefix(literal, `"""`) || strings.HasPrefix(literal, "'''") {
+		quote = literal[:3]
+	}
+	closing := quote
+	if quote == "/" {
+		// A regular expression literal keeps its flags
+		closing = literal[strings.LastIndex(literal, "/"):]
+	}
+	content := strings.TrimSuffix(strings.TrimPrefix(literal, quote), closing)
+	if content == "" {
+		// Keep empty literals as they are
+		out.WriteString(literal)
+		return
+	}
+	out.WriteString(quote)
+	out.WriteString(m.token(content, kindString))
+	out.WriteString(closing)
+}
+
+// writeCode writes code with its identifiers replaced by tokens; the caller must hold m.mu
+func (m *Mapping) writeCode(out *strings.Builder, code string, nextIsString bool) {
+	for i := 0; i < len(code); {
+		if isDigit(code[i]) {
+			// Numeric literals such as 0xFF00, 1e-3, 10L and 3.0f are kept whole
+			end := numberEnd(code, i)
+			out.WriteString(code[i:end])
+			i = end
+			continue
+		}
+		if !isIdentStart(code[i]) {
+			out.WriteByte(code[i])
+			i++
+			continue
+		}
+
+		end := i + 1
+		for end < len(code) && isIdentPart(code[end]) {
+			end++
+		}
+		word := code[i:end]
+		if keywords[word] || (end == len(code) && nextIsString && isStringPrefix(word)) {
+			out.WriteString(word)
+		} else {
+			out.WriteString(m.token(word, identifierKind(word, code[end:])))
+		}
+		i = end
+	}
+}
+
+// Restore maps the tokens in text back to the original names and string contents
+func (m *Mapping) Restore(text string) string {
+	if m == nil {
+		return text
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return tokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		if original, ok := m.reverse[token]; ok {
+			return original
+		}
+		return token
+	})
+}
+
+// token returns the token for a name, assigning the next one of the given kind
+// on first use; the caller must hold m.mu
+func (m *Mapping) token(name, kind string) string {
+	if token, ok := m.forward[name]; ok {
+		return token
+	}
+
+	m.counters[kind]++
+	token := fmt.Sprintf("%s%d", kind, m.counters[kind])
+	m.forward[name] = token
+	m.reverse[token] = name
+	return token
+}
+
+// identifierKind guesses what an identifier names from its first occurrence
+func identifierKind(word, rest string) string {
+	if strings.HasPrefix(strings.TrimLeft(rest, " \t"), "(") {
+		return kindFunction
+	}
+	if unicode.IsUpper(rune(word[0])) {
+		return kindType
+	}
+	return kindVariable
+}
+
+// isStringPrefix reports whether word is a literal prefix such as Python's f"..." or b'...'
+func isStringPrefix(word string) bool {
+	return len(word) <= 2 && strings.Trim(word, "fFrRbBuU") == ""
+}
+
+// numberEnd returns the index just past the numeric literal starting at i,
+// including digit separators, suffixes and signed exponents
+func numberEnd(code string, i int) int {
+	hex := strings.HasPrefix(code[i:], "0x") || strings.HasPrefix(code[i:], "0X")
+	end := i + 1
+	for end < len(code) {
+		ch := code[end]
+		switch {
+		case isNumberPart(ch):
+			end++
+		case (ch == '+' || ch == '-') && end+1 < len(code) && isDigit(code[end+1]) && isExponent(code[end-1], hex):
+			end++
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+// isExponent reports whether ch marks the exponent of a decimal or hexadecimal float
+func isExponent(ch byte, hex bool) bool {
+	if hex {
+		return ch == 'p' || ch == 'P'
+	}
+	return ch == 'e' || ch == 'E'
+}
+
+// isNumberPart reports whether ch can continue a numeric literal, matching [0-9A-Za-z_.]
+func isNumberPart(ch byte) bool {
+	return ch == '_' || ch == '.' || isDigit(ch) || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= 0x80
+}
+
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || isDigit(ch)
+}
@@ -0,0 +1,45 @@
+package anonymize
+
+import "testing"
+
+func TestCodeKeepsNumericLiterals(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		language string
+		want     string
+	}{
+		{name: "hexadecimal", code: "mask = 0xFF00", language: "c", want: "var1 = 0xFF00"},
+		{name: "exponent", code: "limit = 1e3", language: "python", want: "var1 = 1e3"},
+		{name: "negative exponent", code: "eps = 1e-3", language: "python", want: "var1 = 1e-3"},
+		{name: "positive exponent", code: "big = 2.5E+10", language: "java", want: "var1 = 2.5E+10"},
+		{name: "long suffix", code: "long n = 10L;", language: "java", want: "long var1 = 10L;"},
+		{name: "float suffix", code: "float f = 3.0f;", language: "java", want: "float var1 = 3.0f;"},
+		{name: "digit separators", code: "n := 1_000_000", language: "go", want: "var1 := 1_000_000"},
+		{name: "hexadecimal float", code: "x = 0x1.8p-3", language: "c", want: "var1 = 0x1.8p-3"},
+		{name: "subtraction after hex", code: "d = 0xE-1", language: "c", want: "var1 = 0xE-1"},
+		{name: "subtraction", code: "d = n-1", language: "go", want: "var1 = var2-1"},
+		{name: "index", code: "first = items[0]", language: "python", want: "var1 = var2[0]"},
+		{name: "identifier with digits", code: "v2 = 1", language: "python", want: "var1 = 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New().Code(tt.code, tt.language); got != tt.want {
+				t.Errorf("Code(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeKeepsSuffixApartFromVariables(t *testing.T) {
+	mapping := New()
+	got := mapping.Code("float f = 3.0f; return f * 2;", "java")
+	want := "float var1 = 3.0f; return var1 * 2;"
+	if got != want {
+		t.Fatalf("Code() = %q, want %q", got, want)
+	}
+	if restored := mapping.Restore(got); restored != "float f = 3.0f; return f * 2;" {
+		t.Errorf("Restore() = %q", restored)
+	}
+}
@@ -0,0 +1,43 @@
+package anonymize
+
+// keywords are kept as they are: language keywords, primitive types and common
+// built-ins of the supported languages, which reveal nothing proprietary
+var keywords = toSet(
+	// Go
+	"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough",
+	"for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range",
+	"return", "select", "struct", "switch", "type", "var",
+	"bool", "byte", "complex64", "complex128", "error", "float32", "float64", "int", "int8",
+	"int16", "int32", "int64", "rune", "string", "uint", "uint8", "uint16", "uint32", "uint64",
+	"uintptr", "any", "true", "false", "nil", "iota",
+	"append", "cap", "close", "copy", "delete", "len", "make", "new", "panic", "print",
+	"println", "recover", "min", "max", "clear",
+	// JavaScript and TypeScript
+	"async", "await", "catch", "class", "debugger", "do", "export", "extends", "finally",
+	"function", "in", "instanceof", "let", "of", "static", "super", "this", "throw", "try",
+	"typeof", "void", "while", "with", "yield", "null", "undefined", "NaN", "Infinity",
+	"implements", "private", "protected", "public", "readonly", "abstract", "declare", "enum",
+	"keyof", "namespace", "number", "boolean", "unknown", "never", "object", "symbol", "as",
+	"is", "from", "get", "set", "constructor", "console", "log", "Promise", "Array", "Object",
+	"String", "Number", "Boolean", "Map", "Set", "JSON", "Math", "Date", "Error", "require",
+	"module", "exports", "window", "document",
+	// Python
+	"and", "assert", "def", "del", "elif", "except", "global", "lambda", "nonlocal", "not",
+	"or", "pass", "raise", "self", "cls", "None", "True", "False", "dict", "list", "tuple",
+	"str", "float", "range", "enumerate", "zip", "isinstance", "open", "sorted",
+	// C, C++ and Java
+	"auto", "char", "double", "extern", "long", "register", "short", "signed", "sizeof",
+	"typedef", "union", "unsigned", "volatile", "include", "define", "ifdef", "ifndef",
+	"endif", "std", "cout", "endl", "vector", "nullptr", "template", "typename", "virtual",
+	"override", "final", "delete", "operator", "friend", "inline", "explicit", "noexcept",
+	"using", "throws", "synchronized", "transient", "native", "strictfp", "instanceof",
+	"package", "System", "out", "Override", "Integer", "Long", "Double", "Float",
+)
+
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
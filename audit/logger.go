@@ -0,0 +1,222 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxBytes is the log size at which it is rotated
+	DefaultMaxBytes = 10 << 20
+	// DefaultMaxFiles is the number of rotated logs kept
+	DefaultMaxFiles = 5
+)
+
+// Entry records a single call that sent code to a model
+type Entry struct {
+	Time time.Time `json:"time"`
+	// File is the reviewed file, relative to its repository
+	File string `json:"file,omitempty"`
+	// Call is "review" for a single function or "batch"
+	Call      string   `json:"call"`
+	Functions []string `json:"functions"`
+	Model     string   `json:"model"`
+	// PromptHash is the SHA-256 of everything sent to the model
+	PromptHash string `json:"prompt_hash"`
+	// Prompt is the full prompt, recorded only when enabled
+	Prompt         string `json:"prompt,omitempty"`
+	PromptTokens   int    `json:"prompt_tokens"`
+	ResponseTokens int    `json:"response_tokens"`
+	// TokensEstimated is set when the backend reported no usage
+	TokensEstimated bool `json:"tokens_estimated,omitempty"`
+	// Outcome is "ok", "cancelled" or the provider error kind
+	Outcome    string `json:"outcome"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Filter selects entries in a query; zero fields match everything
+type Filter struct {
+	Since time.Time
+	Until time.Time
+	// File matches entries whose file path contains it
+	File string
+}
+
+// Logger appends entries to a JSON lines file, rotating it by size
+type Logger struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu sync.Mutex
+}
+
+// DefaultPath returns the audit log used when none is configured
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "reviewer-bot", "audit.jsonl")
+}
+
+// NewLogger creates a logger writing to path
+func NewLogger(path string, maxBytes int64, maxFiles int) *Logger {
+	if path == "" {
+		path = DefaultPath()
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	return &Logger{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+}
+
+// Log appends an entry, rotating the log first when it is full
+func (l *Logger) Log(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %v", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+	if info, err := os.Stat(l.path); err == nil && info.Size()+int64(len(data)) >= l.maxBytes {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return nil
+}
+
+// Query returns the entries of the current and rotated logs matching the filter,
+// oldest first
+func (l *Logger) Query(filter Filter) ([]Entry, error) {
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, path := range files {
+		fileEntries, err := readEntries(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range fileEntries {
+			if filter.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// matches reports whether an entry passes the filter
+func (f Filter) matches(entry Entry) bool {
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.Time.Before(f.Until) {
+		return false
+	}
+	return f.File == "" || strings.Contains(entry.File, f.File)
+}
+
+// rotate renames the current log with a timestamp and drops the oldest rotated
+// logs beyond the limit; the caller must hold l.mu
+func (l *Logger) rotate() error {
+	ext := filepath.Ext(l.path)
+	rotated := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(l.path, ext), time.Now().UTC().Format("20060102T150405.000000000"), ext)
+	if err := os.Rename(l.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %v", err)
+	}
+
+	old, err := l.rotatedFiles()
+	if err != nil {
+		return err
+	}
+	for len(old) > l.maxFiles {
+		if err := os.Remove(old[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove old audit log: %v", err)
+		}
+		old = old[1:]
+	}
+	return nil
+}
+
+// rotatedFiles lists the rotated logs, oldest first
+func (l *Logger) rotatedFiles() ([]string, error) {
+	ext := filepath.Ext(l.path)
+	matches, err := filepath.Glob(strings.TrimSuffix(l.path, ext) + "-*" + ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %v", err)
+	}
+	// Timestamps sort chronologically
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// files lists the rotated logs followed by the current one
+func (l *Logger) files() ([]string, error) {
+	files, err := l.rotatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(l.path); err == nil {
+		files = append(files, l.path)
+	}
+	return files, nil
+}
+
+// readEntries reads a log file, skipping lines that cannot be parsed
+func readEntries(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	return entries, nil
+}
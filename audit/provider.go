@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"reviewer-bot/prompt"
+	"reviewer-bot/provider"
+	"time"
+)
+
+// fileKey is the context key carrying the reviewed file's path
+type fileKey struct{}
+
+// WithFile returns a context whose provider calls are logged against the given
+// repository-relative file path
+func WithFile(ctx context.Context, file string) context.Context {
+	return context.WithValue(ctx, fileKey{}, file)
+}
+
+// fileFrom returns the file path stored by WithFile
+func fileFrom(ctx context.Context) string {
+	file, _ := ctx.Value(fileKey{}).(string)
+	return file
+}
+
+// Provider records every call made to the wrapped provider in the audit log
+type Provider struct {
+	provider.ReviewProvider
+	logger     *Logger
+	fullPrompt bool
+}
+
+// NewProvider wraps p so every call is logged; fullPrompt also records the prompt text
+func NewProvider(p provider.ReviewProvider, logger *Logger, fullPrompt bool) *Provider {
+	return &Provider{
+		ReviewProvider: p,
+		logger:         logger,
+		fullPrompt:     fullPrompt,
+	}
+}
+
+// GenerateReview generates a review for a single function and logs the call
+func (a *Provider) GenerateReview(ctx context.Context, functionName, functionCode, style string) (provider.Response, error) {
+	start := time.Now()
+	result, err := a.ReviewProvider.GenerateReview(ctx, functionName, functionCode, style)
+	a.record(ctx, "review", style, []string{functionName}, prompt.GetReviewPrompt(style, functionName, functionCode).Text(), start, result, err)
+	return result, err
+}
+
+// GenerateBatchReview generates reviews for multiple functions and logs the call
+func (a *Provider) GenerateBatchReview(ctx context.Context, items []provider.BatchItem, style string) (provider.Response, error) {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+
+	start := time.Now()
+	result, err := a.ReviewProvider.GenerateBatchReview(ctx, items, style)
+	a.record(ctx, "batch", style, names, prompt.GetBatchPrompt(items, style).Text(), start, result, err)
+	return result, err
+}
+
+// record writes the audit entry for a call; a failing audit log is reported but
+// does not fail the review
+func (a *Provider) record(ctx context.Context, call, style string, functions []string, promptText string, start time.Time, result provider.Response, err error) {
+	hash := sha256.Sum256([]byte(promptText))
+	entry := Entry{
+		Time:           start,
+		File:           fileFrom(ctx),
+		Call:           call,
+		Functions:      functions,
+		Model:          a.ModelName(style),
+		PromptHash:     hex.EncodeToString(hash[:]),
+		PromptTokens:   result.Usage.PromptTokens,
+		ResponseTokens: result.Usage.ResponseTokens,
+		Outcome:        "ok",
+		DurationMS:     time.Since(start).Milliseconds(),
+	}
+	if err == nil && entry.PromptTokens == 0 && entry.ResponseTokens == 0 {
+		entry.PromptTokens = provider.EstimateTokens(promptText)
+		entry.ResponseTokens = provider.EstimateTokens(result.Text)
+		entry.TokensEstimated = true
+	}
+	if a.fullPrompt {
+		entry.Prompt = promptText
+	}
+
+	switch {
+	case err == nil:
+	case provider.KindOf(err) != "":
+		entry.Outcome = string(provider.KindOf(err))
+		entry.Error = err.Error()
+	case provider.Cancelled(ctx):
+		entry.Outcome = "cancelled"
+		entry.Error = err.Error()
+	case ctx.Err() != nil:
+		// The attempt ran out of its own time and will be retried
+		entry.Outcome = string(provider.ErrTransient)
+		entry.Error = err.Error()
+	default:
+		entry.Outcome = "error"
+		entry.Error = err.Error()
+	}
+
+	if logErr := a.logger.Log(entry); logErr != nil {
+		log.Printf("Failed to write audit log: %v", logErr)
+	}
+}
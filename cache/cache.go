@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reviewer-bot/types"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTTL is how long a cached review stays valid
+	DefaultTTL = 7 * 24 * time.Hour
+	// DefaultMaxEntries is the number of reviews kept before the least recently used are dropped
+	DefaultMaxEntries = 5000
+
+	fileName = "reviews.json"
+)
+
+// Entry is a single cached review
+type Entry struct {
+	Review    types.Review `json:"review"`
+	CreatedAt time.Time    `json:"created_at"`
+	LastUsed  time.Time    `json:"last_used"`
+}
+
+// Stats summarizes the cache contents
+type Stats struct {
+	Path    string     `json:"path"`
+	Entries int        `json:"entries"`
+	Expired int        `json:"expired"`
+	Bytes   int64      `json:"bytes"`
+	Oldest  *time.Time `json:"oldest,omitempty"`
+	Newest  *time.Time `json:"newest,omitempty"`
+}
+
+// Cache is a persistent on-disk review cache
+type Cache struct {
+	path       string
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*Entry
+	dirty   bool
+}
+
+// DefaultDir returns the cache directory used when none is configured
+func DefaultDir() string {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "reviewer-bot")
+	}
+	return filepath.Join(os.TempDir(), "reviewer-bot")
+}
+
+// Open loads the cache stored in dir, starting empty when there is none yet
+func Open(dir string, ttl time.Duration, maxEntries int) (*Cache, error) {
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+
+	c := &Cache{
+		path:       filepath.Join(dir, fileName),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*Entry),
+	}
+
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review cache: %v", err)
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		// A corrupt cache is not worth failing a review run for
+		c.entries = make(map[string]*Entry)
+		c.dirty = true
+	}
+	return c, nil
+}
+
+// Key builds the cache key for a function's code under a style, model and prompt version
+func Key(code, language, style, model, promptVersion string) string {
+	hash := sha256.New()
+	for _, part := range []string{NormalizeCode(code, language), strings.ToLower(style), model, promptVersion} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get returns the cached review for key, if present and not expired
+func (c *Cache) Get(key string) (types.Review, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.CreatedAt) > c.ttl {
+		return types.Review{}, false
+	}
+	entry.LastUsed = time.Now()
+	c.dirty = true
+	return entry.Review, true
+}
+
+// Put stores a review under key
+func (c *Cache) Put(key string, review types.Review) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.entries[key] = &Entry{Review: review, CreatedAt: now, LastUsed: now}
+	c.dirty = true
+}
+
+// Save prunes the cache and writes it to disk if anything changed
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.prune() > 0 {
+		c.dirty = true
+	}
+	if !c.dirty {
+		return nil
+	}
+	if err := c.write(); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// Prune removes expired entries and trims the cache to its size limit,
+// returning the number of entries removed
+func (c *Cache) Prune() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := c.prune()
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, c.write()
+}
+
+// Clear removes every entry
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*Entry)
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to clear review cache: %v", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// Stats summarizes the cache contents
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := Stats{Path: c.path, Entries: len(c.entries)}
+	if info, err := os.Stat(c.path); err == nil {
+		stats.Bytes = info.Size()
+	}
+	for _, entry := range c.entries {
+		if time.Since(entry.CreatedAt) > c.ttl {
+			stats.Expired++
+		}
+		if stats.Oldest == nil || entry.CreatedAt.Before(*stats.Oldest) {
+			stats.Oldest = &entry.CreatedAt
+		}
+		if stats.Newest == nil || entry.CreatedAt.After(*stats.Newest) {
+			stats.Newest = &entry.CreatedAt
+		}
+	}
+	return stats
+}
+
+// prune drops expired entries and the least recently used ones beyond the
+// size limit; the caller must hold c.mu
+func (c *Cache) prune() int {
+	removed := 0
+	for key, entry := range c.entries {
+		if time.Since(entry.CreatedAt) > c.ttl {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+
+	if len(c.entries) <= c.maxEntries {
+		return removed
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].LastUsed.Before(c.entries[keys[j]].LastUsed)
+	})
+	for _, key := range keys[:len(keys)-c.maxEntries] {
+		delete(c.entries, key)
+		removed++
+	}
+	return removed
+}
+
+// write atomically replaces the cache file; the caller must hold c.mu
+func (c *Cache) write() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode review cache: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), fileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write review cache: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write review cache: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write review cache: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write review cache: %v", err)
+	}
+	return nil
+}
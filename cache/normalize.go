@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"reviewer-bot/lexer"
+	"strings"
+)
+
+// NormalizeCode strips comments and collapses whitespace so that formatting-only
+// edits keep the same cache key; string literals are kept verbatim. Python keeps
+// its line breaks and indentation, relative to the first line, since moving a
+// statement into or out of a block changes what the code does.
+func NormalizeCode(code, language string) string {
+	python := language == "python"
+	baseIndent := code[:len(code)-len(strings.TrimLeft(code, " \t"))]
+
+	var out strings.Builder
+	pending := ""
+	// space records a run of whitespace or a comment between two tokens
+	space := func(whitespace string) {
+		if python {
+			if line := strings.LastIndexByte(whitespace, '\n'); line >= 0 {
+				pending = "\n" + strings.TrimPrefix(whitespace[line+1:], baseIndent)
+				return
+			}
+		}
+		if pending == "" {
+			pending = " "
+		}
+	}
+	emit := func(s string) {
+		if out.Len() > 0 {
+			out.WriteString(pending)
+		}
+		pending = ""
+		out.WriteString(s)
+	}
+
+	for _, segment := range lexer.Split(code, language) {
+		switch segment.Kind {
+		case lexer.Comment:
+			space("")
+		case lexer.String:
+			emit(segment.Text)
+		default:
+			text := segment.Text
+			for text != "" {
+				end := strings.IndexAny(text, " \t\r\n")
+				if end < 0 {
+					end = len(text)
+				}
+				if end > 0 {
+					emit(text[:end])
+					text = text[end:]
+					continue
+				}
+				word := strings.IndexFunc(text, func(r rune) bool { return !isSpace(r) })
+				if word < 0 {
+					word = len(text)
+				}
+				space(strings.ReplaceAll(text[:word], "\r", ""))
+				text = text[word:]
+			}
+		}
+	}
+	return out.String()
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
+}
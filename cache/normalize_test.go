@@ -0,0 +1,58 @@
+package cache
+
+import "testing"
+
+func TestNormalizeCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		language string
+		want     string
+	}{
+		{
+			name:     "collapses whitespace and drops comments",
+			code:     "func a() {\n\t// note\n\treturn  1 /* one */\n}",
+			language: "go",
+			want:     "func a() { return 1 }",
+		},
+		{
+			name:     "keeps string literals",
+			code:     "x := \"a  // b\"",
+			language: "go",
+			want:     "x := \"a  // b\"",
+		},
+		{
+			name:     "keeps python line breaks and indentation",
+			code:     "def a():\n    if x:\n        y()  # call\n\n    z()\n",
+			language: "python",
+			want:     "def a():\n    if x:\n        y()\n    z()",
+		},
+		{
+			name:     "python indentation is relative to the first line",
+			code:     "    def a(self):\n        return  1\n",
+			language: "python",
+			want:     "def a(self):\n    return 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeCode(tt.code, tt.language); got != tt.want {
+				t.Errorf("NormalizeCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCodePythonBlocks(t *testing.T) {
+	inside := "def a():\n    for x in xs:\n        f(x)\n        g()\n"
+	outside := "def a():\n    for x in xs:\n        f(x)\n    g()\n"
+	if NormalizeCode(inside, "python") == NormalizeCode(outside, "python") {
+		t.Error("moving a statement out of a block kept the same normalized code")
+	}
+
+	reformatted := "def a():\n    for x  in xs:   # loop\n\n        f( x)\n    g()\n"
+	if NormalizeCode(reformatted, "python") != NormalizeCode("def a():\n    for x in xs:\n        f( x)\n    g()\n", "python") {
+		t.Error("formatting-only edits changed the normalized code")
+	}
+}
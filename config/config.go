@@ -0,0 +1,396 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"reviewer-bot/types"
+	"reviewer-bot/usage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ProviderGemini selects the Gemini API backend
+	ProviderGemini = "gemini"
+	// ProviderOpenAI selects an OpenAI-compatible chat completions backend
+	ProviderOpenAI = "openai"
+	// ProviderOllama selects a local Ollama server
+	ProviderOllama = "ollama"
+	// ProviderMock selects the mock backend that returns canned reviews
+	ProviderMock = "mock"
+)
+
+const (
+	// DefaultTimeout bounds a whole review run
+	DefaultTimeout = 3 * time.Minute
+	// DefaultCallTimeout bounds a single Gemini call; the OpenAI-compatible and
+	// Ollama clients apply their own request timeouts
+	DefaultCallTimeout = 60 * time.Second
+)
+
+// Config holds the backend configuration
+type Config struct {
+	// Provider is the name of the review backend to use
+	Provider string
+	// APIKey is the API key for the Gemini provider
+	APIKey string
+	// Gemini holds the settings for the Gemini provider
+	Gemini GeminiConfig
+	// Request holds the generation overrides sent with the current request
+	Request types.GenerationOptions
+	// MockMode forces the mock provider regardless of Provider
+	MockMode bool
+	// Timeout bounds the whole review run; finished reviews are returned when it passes
+	Timeout time.Duration
+	// CallTimeout bounds every attempt at a provider call, not counting the rate
+	// limiter wait; 0 uses each backend's default
+	CallTimeout time.Duration
+	// MaxRetries is the number of retries for transient and quota errors
+	MaxRetries int
+	// RetryBaseDelay is the backoff before the first retry
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the backoff and the longest server retry hint honoured
+	RetryMaxDelay time.Duration
+	// BreakerThreshold is the number of consecutive failures that stops further calls
+	BreakerThreshold int
+	// BreakerCooldown is how long calls are skipped once the breaker opens
+	BreakerCooldown time.Duration
+	// Workers is the number of individual reviews generated concurrently
+	Workers int
+	// RequestsPerMinute limits provider calls; 0 means unlimited
+	RequestsPerMinute int
+	// TokensPerMinute limits estimated prompt tokens sent; 0 means unlimited
+	TokensPerMinute int
+	// BatchTokenBudget caps the estimated prompt tokens of each batch call
+	BatchTokenBudget int
+	// MaxFunctionTokens caps the estimated tokens of a single function's code
+	MaxFunctionTokens int
+	// BatchWorkers is the number of batch calls run concurrently
+	BatchWorkers int
+	// OpenAI holds the settings for the OpenAI-compatible provider
+	OpenAI OpenAIConfig
+	// Ollama holds the settings for the Ollama provider
+	Ollama OllamaConfig
+	// Cache holds the settings for the on-disk review cache
+	Cache CacheConfig
+	// Prices maps model names to their price per million tokens
+	Prices usage.PriceTable
+	// LedgerPath is the file recording the usage of every run
+	LedgerPath string
+	// Budget limits the tokens and cost spent per run, per day and per repository
+	Budget usage.Budget
+	// DisableRedaction sends code without masking secrets and personal data
+	DisableRedaction bool
+	// RedactPatterns are extra regular expressions whose matches are masked
+	RedactPatterns []string
+	// Anonymize renames identifiers and string literals before code is sent
+	Anonymize bool
+	// Audit holds the settings for the log of outbound model calls
+	Audit AuditConfig
+}
+
+// GeminiConfig holds the settings for the Gemini provider
+type GeminiConfig struct {
+	Backend    string
+	Project    string
+	Location   string
+	BaseURL    string
+	Generation types.GenerationOptions
+	Styles     map[string]types.GenerationOptions
+}
+
+// OpenAIConfig holds the settings for an OpenAI-compatible server
+type OpenAIConfig struct {
+	BaseURL           string
+	APIKey            string
+	Model             string
+	Timeout           time.Duration
+	AuthHeader        string
+	AuthScheme        string
+	DisableJSONSchema bool
+}
+
+// OllamaConfig holds the settings for a local Ollama server
+type OllamaConfig struct {
+	Host             string
+	Model            string
+	Timeout          time.Duration
+	DisableStreaming bool
+}
+
+// CacheConfig holds the settings for the on-disk review cache
+type CacheConfig struct {
+	Dir        string
+	TTL        time.Duration
+	MaxEntries int
+	Disabled   bool
+}
+
+// AuditConfig holds the settings for the log of outbound model calls
+type AuditConfig struct {
+	Path       string
+	MaxBytes   int64
+	MaxFiles   int
+	FullPrompt bool
+	Disabled   bool
+}
+
+// Load reads the configuration from environment variables
+func Load() *Config {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("REVIEWER_PROVIDER")))
+	if provider == "" {
+		provider = ProviderGemini
+	}
+
+	// Running without the configured redaction would send what it should hide
+	redactPatterns, err := envStrings("REVIEWER_REDACT_PATTERNS")
+	if err != nil {
+		log.Fatalf("Failed to read redaction patterns: %v", err)
+	}
+
+	// Ignoring a malformed limit would lift it, so it stops the run instead
+	var budget usage.Budget
+	for prefix, limit := range map[string]*usage.Limit{
+		"REVIEWER_BUDGET_RUN":  &budget.Run,
+		"REVIEWER_BUDGET_DAY":  &budget.Day,
+		"REVIEWER_BUDGET_REPO": &budget.Repo,
+	} {
+		if *limit, err = envLimit(prefix); err != nil {
+			log.Fatalf("Failed to read spending budget: %v", err)
+		}
+	}
+
+	return &Config{
+		Provider:          provider,
+		APIKey:            os.Getenv("GEMINI_API_KEY"),
+		MockMode:          strings.ToLower(os.Getenv("MOCK_MODE")) == "true",
+		Timeout:           envDuration("REVIEWER_TIMEOUT", DefaultTimeout),
+		CallTimeout:       envDuration("REVIEWER_CALL_TIMEOUT", 0),
+		MaxRetries:        envInt("REVIEWER_MAX_RETRIES", 3),
+		RetryBaseDelay:    envDuration("REVIEWER_RETRY_BASE_DELAY", 500*time.Millisecond),
+		RetryMaxDelay:     envDuration("REVIEWER_RETRY_MAX_DELAY", 30*time.Second),
+		BreakerThreshold:  envInt("REVIEWER_BREAKER_THRESHOLD", 5),
+		BreakerCooldown:   envDuration("REVIEWER_BREAKER_COOLDOWN", 30*time.Second),
+		Workers:           envInt("REVIEWER_WORKERS", 4),
+		RequestsPerMinute: envInt("REVIEWER_REQUESTS_PER_MINUTE", 0),
+		TokensPerMinute:   envInt("REVIEWER_TOKENS_PER_MINUTE", 0),
+		BatchTokenBudget:  envInt("REVIEWER_BATCH_TOKEN_BUDGET", 8000),
+		MaxFunctionTokens: envInt("REVIEWER_MAX_FUNCTION_TOKENS", 2000),
+		BatchWorkers:      envInt("REVIEWER_BATCH_WORKERS", 1),
+		Gemini: GeminiConfig{
+			Backend:  strings.ToLower(os.Getenv("GEMINI_BACKEND")),
+			Project:  os.Getenv("GOOGLE_CLOUD_PROJECT"),
+			Location: os.Getenv("GOOGLE_CLOUD_LOCATION"),
+			BaseURL:  os.Getenv("GEMINI_BASE_URL"),
+			Generation: types.GenerationOptions{
+				Model:             os.Getenv("GEMINI_MODEL"),
+				Temperature:       envFloat32("GEMINI_TEMPERATURE"),
+				TopP:              envFloat32("GEMINI_TOP_P"),
+				MaxOutputTokens:   envInt32("GEMINI_MAX_OUTPUT_TOKENS"),
+				Seed:              envInt32Ptr("GEMINI_SEED"),
+				SafetySettings:    envPairs("GEMINI_SAFETY_SETTINGS"),
+				SystemInstruction: os.Getenv("GEMINI_SYSTEM_INSTRUCTION"),
+			},
+			Styles: envStyleOptions("GEMINI_STYLE_OPTIONS"),
+		},
+		OpenAI: OpenAIConfig{
+			BaseURL:    os.Getenv("OPENAI_BASE_URL"),
+			APIKey:     os.Getenv("OPENAI_API_KEY"),
+			Model:      os.Getenv("OPENAI_MODEL"),
+			Timeout:    envDuration("OPENAI_TIMEOUT", 0),
+			AuthHeader: os.Getenv("OPENAI_AUTH_HEADER"),
+			AuthScheme: os.Getenv("OPENAI_AUTH_SCHEME"),
+			// Some servers reject response_format with a JSON schema
+			DisableJSONSchema: strings.ToLower(os.Getenv("OPENAI_JSON_SCHEMA")) == "false",
+		},
+		Ollama: OllamaConfig{
+			Host:             os.Getenv("OLLAMA_HOST"),
+			Model:            os.Getenv("OLLAMA_MODEL"),
+			Timeout:          envDuration("OLLAMA_TIMEOUT", 0),
+			DisableStreaming: strings.ToLower(os.Getenv("OLLAMA_STREAM")) == "false",
+		},
+		Prices:           usage.NewPriceTable(envPrices("REVIEWER_PRICES")),
+		LedgerPath:       os.Getenv("REVIEWER_LEDGER_PATH"),
+		Budget:           budget,
+		DisableRedaction: strings.ToLower(os.Getenv("REVIEWER_REDACT")) == "false",
+		RedactPatterns:   redactPatterns,
+		Anonymize:        strings.ToLower(os.Getenv("REVIEWER_ANONYMIZE")) == "true",
+		Audit: AuditConfig{
+			Path:       os.Getenv("REVIEWER_AUDIT_LOG"),
+			MaxBytes:   int64(envInt("REVIEWER_AUDIT_MAX_MB", 10)) << 20,
+			MaxFiles:   envInt("REVIEWER_AUDIT_MAX_FILES", 5),
+			FullPrompt: strings.ToLower(os.Getenv("REVIEWER_AUDIT_FULL_PROMPT")) == "true",
+			Disabled:   strings.ToLower(os.Getenv("REVIEWER_AUDIT")) == "false",
+		},
+		Cache: CacheConfig{
+			Dir:        os.Getenv("REVIEWER_CACHE_DIR"),
+			TTL:        envDuration("REVIEWER_CACHE_TTL", 7*24*time.Hour),
+			MaxEntries: envInt("REVIEWER_CACHE_MAX_ENTRIES", 5000),
+			Disabled:   strings.ToLower(os.Getenv("REVIEWER_NO_CACHE")) == "true",
+		},
+	}
+}
+
+// envDuration reads a duration such as "30s" from the environment
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %v, using default", name, err)
+		return fallback
+	}
+	return duration
+}
+
+// envInt reads an integer from the environment
+func envInt(name string, fallback int) int {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s: %v, using default", name, err)
+		return fallback
+	}
+	return parsed
+}
+
+// envLimit reads a token and cost limit from the <prefix>_TOKENS and <prefix>_COST
+// variables; unset ones are unlimited, malformed or negative ones are an error
+func envLimit(prefix string) (usage.Limit, error) {
+	var limit usage.Limit
+	if value := strings.TrimSpace(os.Getenv(prefix + "_TOKENS")); value != "" {
+		tokens, err := strconv.Atoi(value)
+		if err != nil || tokens < 0 {
+			return usage.Limit{}, fmt.Errorf("invalid token limit %q for %s_TOKENS", value, prefix)
+		}
+		limit.Tokens = tokens
+	}
+	if value := strings.TrimSpace(os.Getenv(prefix + "_COST")); value != "" {
+		cost, err := strconv.ParseFloat(value, 64)
+		if err != nil || cost < 0 || math.IsInf(cost, 0) || math.IsNaN(cost) {
+			return usage.Limit{}, fmt.Errorf("invalid cost limit %q for %s_COST", value, prefix)
+		}
+		limit.Cost = cost
+	}
+	return limit, nil
+}
+
+// envFloat32 reads an optional float from the environment
+func envFloat32(name string) *float32 {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		log.Printf("Invalid number for %s: %v, ignoring", name, err)
+		return nil
+	}
+	result := float32(parsed)
+	return &result
+}
+
+// envInt32 reads an integer from the environment, returning 0 when unset
+func envInt32(name string) int32 {
+	if value := envInt32Ptr(name); value != nil {
+		return *value
+	}
+	return 0
+}
+
+// envInt32Ptr reads an optional integer from the environment
+func envInt32Ptr(name string) *int32 {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		log.Printf("Invalid integer for %s: %v, ignoring", name, err)
+		return nil
+	}
+	result := int32(parsed)
+	return &result
+}
+
+// envPairs reads comma-separated KEY=VALUE pairs from the environment
+func envPairs(name string) map[string]string {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil
+	}
+
+	pairs := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		key, val, found := strings.Cut(item, "=")
+		if !found {
+			log.Printf("Invalid entry %q in %s, expected KEY=VALUE", item, name)
+			continue
+		}
+		pairs[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return pairs
+}
+
+// envStyleOptions reads a JSON object mapping review styles to generation options
+func envStyleOptions(name string) map[string]types.GenerationOptions {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil
+	}
+
+	var styles map[string]types.GenerationOptions
+	if err := json.Unmarshal([]byte(value), &styles); err != nil {
+		log.Printf("Invalid JSON for %s: %v, ignoring", name, err)
+		return nil
+	}
+
+	normalized := make(map[string]types.GenerationOptions, len(styles))
+	for style, options := range styles {
+		normalized[strings.ToLower(style)] = options
+	}
+	return normalized
+}
+
+// envStrings parses a JSON array of strings
+func envStrings(name string) ([]string, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil, nil
+	}
+
+	var values []string
+	if err := json.Unmarshal([]byte(value), &values); err != nil {
+		return nil, fmt.Errorf("invalid JSON for %s: %v", name, err)
+	}
+	return values, nil
+}
+
+// envPrices parses a JSON object of model prices, e.g.
+// {"gemini-2.5-flash": {"input": 0.3, "output": 2.5}}
+func envPrices(name string) map[string]usage.Price {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return nil
+	}
+
+	var prices map[string]usage.Price
+	if err := json.Unmarshal([]byte(value), &prices); err != nil {
+		log.Printf("Invalid JSON for %s: %v, ignoring", name, err)
+		return nil
+	}
+	return prices
+}
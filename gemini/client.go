@@ -2,272 +2,313 @@ package gemini
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math/rand"
-	"os"
+	"reviewer-bot/prompt"
+	"reviewer-bot/provider"
+	"reviewer-bot/types"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"google.golang.org/genai"
 )
 
+const (
+	// DefaultModel is the Gemini model used when none is configured
+	DefaultModel = "gemini-2.0-flash-exp"
+	// BackendGemini selects the Gemini Developer API
+	BackendGemini = "gemini"
+	// BackendVertex selects Vertex AI
+	BackendVertex = "vertex"
+)
+
+// Config holds the settings for the Gemini client
+type Config struct {
+	// APIKey is required for the Gemini Developer API backend
+	APIKey string
+	// Backend is either BackendGemini (default) or BackendVertex
+	Backend string
+	// Project is the GCP project used by the Vertex backend
+	Project string
+	// Location is the GCP region used by the Vertex backend
+	Location string
+	// BaseURL overrides the API endpoint, e.g. to target a local fake
+	BaseURL string
+	// Generation holds the default generation parameters
+	Generation types.GenerationOptions
+	// Styles holds per-style overrides applied on top of Generation
+	Styles map[string]types.GenerationOptions
+	// Request holds per-request overrides applied last
+	Request types.GenerationOptions
+}
+
 // Client represents a Gemini API client using the official library
 type Client struct {
-	APIKey string
+	config Config
+
+	mu     sync.Mutex
 	client *genai.Client
 }
 
 // NewClient creates a new Gemini client using the official library
-func NewClient(apiKey string) *Client {
+func NewClient(config Config) *Client {
+	if config.Backend == "" {
+		config.Backend = BackendGemini
+	}
+	if config.Generation.Model == "" {
+		config.Generation.Model = DefaultModel
+	}
+
 	return &Client{
-		APIKey: apiKey,
+		config: config,
 	}
 }
 
-// GetReviewPrompt returns a prompt based on the review style
-func GetReviewPrompt(style, functionName, functionCode string) string {
-	basePrompt := fmt.Sprintf(`You are a code reviewer. Review this function and provide a one-liner review in the specified style.
-
-Function: %s
-Code:
-%s
-
-Style: %s
+// Capabilities reports which features the Gemini client supports
+func (c *Client) Capabilities() provider.Capabilities {
+	return provider.Capabilities{Batch: true}
+}
 
-Rate the code quality from 1-5 stars and provide ONLY a one-liner review (max 100 characters) that matches the style. Include appropriate emojis.
+// ModelName returns the name of the Gemini model used for reviews in the given
+// style, which may be overridden in the style's options
+func (c *Client) ModelName(style string) string {
+	return c.optionsForStyle(style).Model
+}
 
-Format your response as: "⭐⭐⭐⭐⭐ Review text here" (use 1-5 stars based on quality)
+// GenerateReview generates a review for a function using Gemini API
+func (c *Client) GenerateReview(ctx context.Context, functionName, functionCode, style string) (provider.Response, error) {
+	return c.generate(ctx, prompt.GetReviewPrompt(style, functionName, functionCode), style, nil)
+}
 
-IMPORTANT: Do not include detailed scoring, analysis, or explanations. Just the star rating and review text.`, functionName, functionCode, style)
+// GenerateBatchReview generates reviews for multiple functions in a single API call
+func (c *Client) GenerateBatchReview(ctx context.Context, items []provider.BatchItem, style string) (provider.Response, error) {
+	return c.generate(ctx, prompt.GetBatchPrompt(items, style), style, batchResponseSchema)
+}
 
-	switch strings.ToLower(style) {
-	case "roast":
-		return basePrompt + "\n\nBe sarcastic and roast the code. Use 🔥 or 😂 emojis."
-	case "funny":
-		return basePrompt + "\n\nBe humorous and light-hearted. Use 😄 or 🤣 emojis."
-	case "motivational":
-		return basePrompt + "\n\nBe encouraging and motivational. Use 💪 or ⭐ emojis."
-	case "technical":
-		return basePrompt + "\n\nBe professional and technical. Use 🔧 or 📊 emojis."
-	case "hilarious":
-		return basePrompt + "\n\nBe extremely funny and over-the-top. Use 🤪 or 🎭 emojis."
-	default:
-		return basePrompt + "\n\nBe neutral and constructive."
-	}
+// batchResponseSchema constrains batch replies to a JSON array of {id, stars, review}
+// with optional sub-scores
+var batchResponseSchema = &genai.Schema{
+	Type: genai.TypeArray,
+	Items: &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"id":     {Type: genai.TypeString},
+			"stars":  {Type: genai.TypeInteger},
+			"review": {Type: genai.TypeString},
+			"scores": {
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"readability":      {Type: genai.TypeInteger},
+					"correctness_risk": {Type: genai.TypeInteger},
+					"complexity":       {Type: genai.TypeInteger},
+					"error_handling":   {Type: genai.TypeInteger},
+					"naming":           {Type: genai.TypeInteger},
+				},
+				PropertyOrdering: []string{"readability", "correctness_risk", "complexity", "error_handling", "naming"},
+			},
+		},
+		Required:         []string{"id", "stars", "review"},
+		PropertyOrdering: []string{"id", "stars", "review", "scores"},
+	},
 }
 
-// GenerateReview generates a review for a function using Gemini API
-func (c *Client) GenerateReview(functionName, functionCode, style string) (string, error) {
-	// Check if we're in mock mode or if no API key is provided
-	if os.Getenv("MOCK_MODE") == "true" || c.APIKey == "" {
-		return c.generateMockReview(functionName, style), nil
+// generate sends a prompt with the generation parameters for the given style,
+// requesting JSON output when a response schema is given. The prompt's
+// instructions go in the system instruction, ahead of any configured one.
+func (c *Client) generate(ctx context.Context, reviewPrompt prompt.Prompt, style string, schema *genai.Schema) (provider.Response, error) {
+	client, err := c.ensureClient(ctx)
+	if err != nil {
+		return provider.Response{}, err
 	}
 
-	// Initialize the client if not already done
-	if c.client == nil {
-		ctx := context.Background()
-		client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: c.APIKey, Backend: genai.BackendGeminiAPI})
-		if err != nil {
-			return "", fmt.Errorf("failed to create Gemini client: %v", err)
-		}
-		c.client = client
+	options := c.optionsForStyle(style)
+	if options.SystemInstruction != "" {
+		options.SystemInstruction = reviewPrompt.System + "\n\n" + options.SystemInstruction
+	} else {
+		options.SystemInstruction = reviewPrompt.System
+	}
+	contentConfig := buildContentConfig(options)
+	if schema != nil {
+		contentConfig.ResponseMIMEType = "application/json"
+		contentConfig.ResponseSchema = schema
 	}
 
-	prompt := GetReviewPrompt(style, functionName, functionCode)
-
-	ctx := context.Background()
-	result, err := c.client.Models.GenerateContent(
+	result, err := client.Models.GenerateContent(
 		ctx,
-		"gemini-2.0-flash-exp",
-		genai.Text(prompt),
-		nil,
+		options.Model,
+		genai.Text(reviewPrompt.User),
+		contentConfig,
 	)
 
 	if err != nil {
-		// Check for specific error types
-		if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "quota") {
-			return "", fmt.Errorf("API quota exceeded. Please check your Gemini API plan or try again later. Error: %v", err)
-		}
-		if strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "unauthorized") {
-			return "", fmt.Errorf("Invalid API key. Please check your Gemini API key. Error: %v", err)
-		}
-		return "", fmt.Errorf("Gemini API error: %v", err)
+		return provider.Response{}, classifyError(ctx, err)
 	}
 
 	if result.Text() == "" {
-		return "", fmt.Errorf("no response from Gemini API")
+		return provider.Response{}, &provider.Error{Kind: provider.ErrTransient, Err: fmt.Errorf("no response from Gemini API")}
 	}
 
 	review := strings.TrimSpace(result.Text())
-	return review, nil
+	return provider.Response{Text: review, Usage: usageOf(result)}, nil
 }
 
-// GenerateBatchReview generates reviews for multiple functions in a single API call
-func (c *Client) GenerateBatchReview(batchPrompt, style string) (string, error) {
-	// Check if we're in mock mode or if no API key is provided
-	if os.Getenv("MOCK_MODE") == "true" || c.APIKey == "" {
-		return c.generateMockBatchReview(style), nil
+// usageOf reads the token counts from a response's usage metadata; thinking
+// tokens are billed as output
+func usageOf(result *genai.GenerateContentResponse) types.Usage {
+	metadata := result.UsageMetadata
+	if metadata == nil {
+		return types.Usage{}
 	}
 
-	// Initialize the client if not already done
+	usage := types.Usage{
+		PromptTokens:   int(metadata.PromptTokenCount),
+		ResponseTokens: int(metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount),
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.ResponseTokens
+	return usage
+}
+
+// ensureClient initializes the genai client on first use
+func (c *Client) ensureClient(ctx context.Context) (*genai.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.client == nil {
-		ctx := context.Background()
-		client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: c.APIKey, Backend: genai.BackendGeminiAPI})
+		client, err := genai.NewClient(ctx, c.clientConfig())
 		if err != nil {
-			return "", fmt.Errorf("failed to create Gemini client: %v", err)
+			return nil, &provider.Error{Kind: provider.ErrAuth, Err: fmt.Errorf("failed to create Gemini client: %v", err)}
 		}
 		c.client = client
 	}
+	return c.client, nil
+}
 
-	prompt := fmt.Sprintf(`%s
-
-Style: %s
-
-Rate each function from 1-5 stars and provide ONLY one-liner reviews (max 100 characters each) that match the style. Include appropriate emojis.
+// classifyError converts a genai error into a typed provider error
+func classifyError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("Gemini API call cancelled: %w", ctx.Err())
+	}
 
-IMPORTANT: Do not include detailed scoring, analysis, or explanations. Just the star rating and review text for each function.`, batchPrompt, style)
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		// Network failures and other errors without a status
+		return &provider.Error{Kind: provider.ErrTransient, Err: fmt.Errorf("Gemini API error: %v", err)}
+	}
 
-	ctx := context.Background()
-	result, err := c.client.Models.GenerateContent(
-		ctx,
-		"gemini-2.0-flash-exp",
-		genai.Text(prompt),
-		nil,
-	)
+	kind := provider.KindForStatus(apiErr.Code)
+	providerErr := &provider.Error{Kind: kind, StatusCode: apiErr.Code, RetryAfter: retryDelay(apiErr)}
+	switch kind {
+	case provider.ErrQuota:
+		providerErr.Err = fmt.Errorf("API quota exceeded. Please check your Gemini API plan or try again later. Error: %v", err)
+	case provider.ErrAuth:
+		providerErr.Err = fmt.Errorf("Invalid API key. Please check your Gemini API key. Error: %v", err)
+	default:
+		providerErr.Err = fmt.Errorf("Gemini API error: %v", err)
+	}
+	return providerErr
+}
 
-	if err != nil {
-		// Check for specific error types
-		if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "quota") {
-			return "", fmt.Errorf("API quota exceeded. Please check your Gemini API plan or try again later. Error: %v", err)
+// retryDelay extracts the server retry hint from a google.rpc.RetryInfo error detail
+func retryDelay(apiErr genai.APIError) time.Duration {
+	for _, detail := range apiErr.Details {
+		detailType, _ := detail["@type"].(string)
+		if !strings.HasSuffix(detailType, "RetryInfo") {
+			continue
 		}
-		if strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "unauthorized") {
-			return "", fmt.Errorf("Invalid API key. Please check your Gemini API key. Error: %v", err)
+		if value, ok := detail["retryDelay"].(string); ok {
+			if delay, err := time.ParseDuration(value); err == nil {
+				return delay
+			}
 		}
-		return "", fmt.Errorf("Gemini API error: %v", err)
 	}
+	return 0
+}
 
-	if result.Text() == "" {
-		return "", fmt.Errorf("no response from Gemini API")
+// clientConfig builds the genai client configuration for the selected backend
+func (c *Client) clientConfig() *genai.ClientConfig {
+	clientConfig := &genai.ClientConfig{
+		APIKey:  c.config.APIKey,
+		Backend: genai.BackendGeminiAPI,
 	}
-
-	review := strings.TrimSpace(result.Text())
-	return review, nil
+	if c.config.Backend == BackendVertex {
+		clientConfig.APIKey = ""
+		clientConfig.Backend = genai.BackendVertexAI
+		clientConfig.Project = c.config.Project
+		clientConfig.Location = c.config.Location
+	}
+	if c.config.BaseURL != "" {
+		clientConfig.HTTPOptions.BaseURL = c.config.BaseURL
+	}
+	return clientConfig
 }
 
-// generateMockBatchReview generates mock batch reviews
-func (c *Client) generateMockBatchReview(style string) string {
-	mockReviews := map[string][]string{
-		"funny": {
-			"😄 This function is doing its best!",
-			"🤣 Well, it's not the worst thing I've seen today",
-			"😊 Simple and effective - like a good dad joke",
-			"🎯 Gets the job done, no questions asked!",
-			"🚀 This function is going places!",
-		},
-		"roast": {
-			"🔥 This function needs a reality check!",
-			"😂 At least it's not the worst code ever!",
-			"🤦‍♂️ I've seen better code in a tutorial!",
-			"💀 This function is barely alive!",
-			"🤡 Clown code that somehow works!",
-		},
-		"motivational": {
-			"💪 You're doing great! This function rocks!",
-			"⭐ Keep up the excellent work!",
-			"🚀 This function is going places!",
-			"🌟 You're making progress!",
-			"🎯 Every function counts!",
-		},
-		"technical": {
-			"🔧 Well-structured and efficient",
-			"📊 Good separation of concerns",
-			"⚡ Performance looks optimized",
-			"🎯 Clean and maintainable",
-			"📈 Scalable design pattern",
-		},
-		"hilarious": {
-			"🤪 This function is so wild, it needs a leash!",
-			"🎭 Drama queen of functions right here!",
-			"🤡 Clown code that somehow works!",
-			"🎪 Welcome to the circus!",
-			"🎨 Picasso would be proud!",
-		},
+// optionsForStyle resolves the generation parameters: defaults, then style, then request
+func (c *Client) optionsForStyle(style string) types.GenerationOptions {
+	options := c.config.Generation
+	if styleOptions, ok := c.config.Styles[strings.ToLower(style)]; ok {
+		options = mergeOptions(options, styleOptions)
 	}
+	return mergeOptions(options, c.config.Request)
+}
 
-	reviews, exists := mockReviews[strings.ToLower(style)]
-	if !exists {
-		reviews = mockReviews["funny"]
+// mergeOptions returns base with every field set in override replaced
+func mergeOptions(base, override types.GenerationOptions) types.GenerationOptions {
+	if override.Model != "" {
+		base.Model = override.Model
 	}
-
-	// Generate multiple mock reviews with generic function names
-	var result strings.Builder
-	for i, review := range reviews {
-		starCount := rand.Intn(3) + 3 // 3-5 stars
-		stars := ""
-		for j := 0; j < starCount; j++ {
-			stars += "⭐"
+	if override.Temperature != nil {
+		base.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		base.TopP = override.TopP
+	}
+	if override.MaxOutputTokens > 0 {
+		base.MaxOutputTokens = override.MaxOutputTokens
+	}
+	if override.Seed != nil {
+		base.Seed = override.Seed
+	}
+	if len(override.SafetySettings) > 0 {
+		merged := make(map[string]string, len(base.SafetySettings)+len(override.SafetySettings))
+		for category, threshold := range base.SafetySettings {
+			merged[category] = threshold
+		}
+		for category, threshold := range override.SafetySettings {
+			merged[category] = threshold
 		}
-		// Use generic function names that will be replaced by actual names
-		result.WriteString(fmt.Sprintf("Function%d: %s %s\n", i+1, stars, review))
+		base.SafetySettings = merged
 	}
-
-	return result.String()
+	if override.SystemInstruction != "" {
+		base.SystemInstruction = override.SystemInstruction
+	}
+	return base
 }
 
-// generateMockReview generates a mock review for testing
-func (c *Client) generateMockReview(functionName, style string) string {
-	mockReviews := map[string][]string{
-		"roast": {
-			"🔥 This function is more confusing than your ex's texts",
-			"😂 I've seen better code in a fortune cookie",
-			"🤦‍♂️ This function has more bugs than a picnic",
-			"😅 At least it compiles... barely",
-			"🤷‍♂️ It works, but at what cost?",
-		},
-		"funny": {
-			"😄 This function is so clean, it sparkles! ✨",
-			"🤣 Well, it's not the worst thing I've seen today",
-			"😊 Simple and effective - like a good dad joke",
-			"🎉 This function deserves a party!",
-			"😎 Cool function, bro!",
-		},
-		"motivational": {
-			"💪 You're doing great! This function rocks!",
-			"⭐ Keep up the excellent work!",
-			"🚀 This function is going places!",
-			"🌟 You've got this! Amazing job!",
-			"🔥 You're on fire! Keep coding!",
-		},
-		"technical": {
-			"🔧 Well-structured and efficient",
-			"📊 Good separation of concerns",
-			"⚡ Performance looks optimized",
-			"🛡️ Proper error handling implemented",
-			"📝 Clean and readable code",
-		},
-		"hilarious": {
-			"🤪 This function is so wild, it needs a leash! 🦮",
-			"🎭 Drama queen of functions right here! 👑",
-			"🤡 Clown code that somehow works! 🤹‍♂️",
-			"🎪 Welcome to the circus of functions! 🎪",
-			"🦄 Unicorn code - magical but questionable! ✨",
-		},
+// buildContentConfig converts generation options into a genai request config
+func buildContentConfig(options types.GenerationOptions) *genai.GenerateContentConfig {
+	config := &genai.GenerateContentConfig{
+		Temperature:     options.Temperature,
+		TopP:            options.TopP,
+		MaxOutputTokens: options.MaxOutputTokens,
+		Seed:            options.Seed,
 	}
-
-	reviews, exists := mockReviews[strings.ToLower(style)]
-	if !exists {
-		reviews = mockReviews["funny"]
+	if options.SystemInstruction != "" {
+		config.SystemInstruction = genai.NewContentFromText(options.SystemInstruction, genai.RoleUser)
 	}
 
-	// Use function name to determine which mock review to use
-	index := len(functionName) % len(reviews)
-	review := reviews[index]
-
-	// Add random star rating (3-5 stars for mock reviews)
-	starCount := rand.Intn(3) + 3 // 3-5 stars
-	stars := ""
-	for i := 0; i < starCount; i++ {
-		stars += "⭐"
+	// Sort categories so requests are deterministic
+	categories := make([]string, 0, len(options.SafetySettings))
+	for category := range options.SafetySettings {
+		categories = append(categories, category)
 	}
-
-	return stars + " " + review
+	sort.Strings(categories)
+	for _, category := range categories {
+		config.SafetySettings = append(config.SafetySettings, &genai.SafetySetting{
+			Category:  genai.HarmCategory(strings.ToUpper(category)),
+			Threshold: genai.HarmBlockThreshold(strings.ToUpper(options.SafetySettings[category])),
+		})
+	}
+	return config
 }
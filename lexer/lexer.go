@@ -0,0 +1,232 @@
+package lexer
+
+import (
+	"sort"
+	"strings"
+)
+
+// Kind classifies a segment of source code
+type Kind int
+
+const (
+	// Code is anything outside comments and string literals
+	Code Kind = iota
+	// Comment is a line or block comment, delimiters included
+	Comment
+	// String is a string, character or regular expression literal, quotes included
+	String
+)
+
+// Segment is a run of source text of a single kind
+type Segment struct {
+	Kind Kind
+	Text string
+	// Offset is the byte offset of the segment in the source
+	Offset int
+}
+
+// regexKeywords are the keywords after which a slash starts a regular expression
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+	"yield": true, "await": true,
+}
+
+// Split divides source into code, comment and string segments using the comment
+// and quoting rules of the language. Unterminated comments and strings run to
+// the end of the source, or of the line for single-line strings.
+func Split(source, language string) []Segment {
+	python := language == "python"
+	tripleQuoted := python || language == "dart"
+	regexLiterals := language == "javascript" || language == "typescript"
+
+	var segments []Segment
+	codeStart := 0
+	flush := func(end int) {
+		if end > codeStart {
+			segments = append(segments, Segment{Kind: Code, Text: source[codeStart:end], Offset: codeStart})
+		}
+	}
+
+	// last is the index of the last non-space code or literal byte, used to tell
+	// a regular expression literal from a division
+	last := -1
+	for i := 0; i < len(source); {
+		kind, end := Code, i
+		switch {
+		case python && source[i] == '#':
+			kind, end = Comment, lineEnd(source, i)
+		case !python && strings.HasPrefix(source[i:], "//"):
+			kind, end = Comment, lineEnd(source, i)
+		case !python && strings.HasPrefix(source[i:], "/*"):
+			kind, end = Comment, blockEnd(source, i+2, "*/")
+		case tripleQuoted && (strings.HasPrefix(source[i:], `"""`) || strings.HasPrefix(source[i:], "'''")):
+			kind, end = String, blockEnd(source, i+3, source[i:i+3])
+		case source[i] == '"' || source[i] == '\'' || (!python && source[i] == '`'):
+			kind, end = String, stringEnd(source, i)
+		case regexLiterals && source[i] == '/' && regexAllowed(source, last):
+			if literalEnd, ok := regexEnd(source, i); ok {
+				kind, end = String, literalEnd
+			}
+		}
+
+		if kind == Code {
+			if !isSpace(source[i]) {
+				last = i
+			}
+			i++
+			continue
+		}
+		flush(i)
+		segments = append(segments, Segment{Kind: kind, Text: source[i:end], Offset: i})
+		if kind == String {
+			last = end - 1
+		}
+		i, codeStart = end, end
+	}
+	flush(len(source))
+	return segments
+}
+
+// Index returns the offset of the first ch at or after from that lies outside
+// comments and strings, or -1 when there is none
+func Index(segments []Segment, from int, ch byte) int {
+	for _, segment := range segments[segmentAt(segments, from):] {
+		if segment.Kind != Code {
+			continue
+		}
+		start := max(from-segment.Offset, 0)
+		if index := strings.IndexByte(segment.Text[start:], ch); index >= 0 {
+			return segment.Offset + start + index
+		}
+	}
+	return -1
+}
+
+// MatchBrace returns the offset of the brace closing the one at open, counting
+// only braces outside comments and strings, or -1 when it is never closed
+func MatchBrace(segments []Segment, open int) int {
+	depth := 0
+	for _, segment := range segments[segmentAt(segments, open):] {
+		if segment.Kind != Code {
+			continue
+		}
+		for i := max(open-segment.Offset, 0); i < len(segment.Text); i++ {
+			switch segment.Text[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+				if depth == 0 {
+					return segment.Offset + i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// segmentAt returns the index of the segment containing offset
+func segmentAt(segments []Segment, offset int) int {
+	index := sort.Search(len(segments), func(i int) bool {
+		return segments[i].Offset > offset
+	})
+	return max(index-1, 0)
+}
+
+// lineEnd returns the index of the newline ending the line at i
+func lineEnd(source string, i int) int {
+	if end := strings.IndexByte(source[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(source)
+}
+
+// blockEnd returns the index just past the closing delimiter searched from i
+func blockEnd(source string, i int, closing string) int {
+	if end := strings.Index(source[i:], closing); end >= 0 {
+		return i + end + len(closing)
+	}
+	return len(source)
+}
+
+// stringEnd returns the index just past the string literal starting at i;
+// backquoted strings may span lines, others stop at the end of the line
+func stringEnd(source string, i int) int {
+	quote := source[i]
+	for j := i + 1; j < len(source); j++ {
+		switch source[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			return j + 1
+		case '\n':
+			if quote != '`' {
+				return j
+			}
+		}
+	}
+	return len(source)
+}
+
+// regexAllowed reports whether a JavaScript slash following the byte at last
+// starts a regular expression literal rather than a division
+func regexAllowed(source string, last int) bool {
+	if last < 0 {
+		return true
+	}
+	switch c := source[last]; {
+	case c == ')' || c == ']' || c == '}' || c == '"' || c == '\'' || c == '`' || c == '/':
+		return false
+	case isWordByte(c):
+		start := last
+		for start > 0 && isWordByte(source[start-1]) {
+			start--
+		}
+		return regexKeywords[source[start:last+1]]
+	}
+	return true
+}
+
+// regexEnd returns the index just past the regular expression literal and its
+// flags starting at i, or false when the slash does not start one on this line
+func regexEnd(source string, i int) (int, bool) {
+	inClass := false
+	for j := i + 1; j < len(source); j++ {
+		switch source[j] {
+		case '\\':
+			j++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return 0, false
+		case '/':
+			if inClass {
+				continue
+			}
+			if j == i+1 {
+				return 0, false
+			}
+			j++
+			for j < len(source) && isWordByte(source[j]) {
+				j++
+			}
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// isSpace reports whether c is a space, tab or line break
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isWordByte reports whether c can be part of an identifier or number
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,153 @@
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		language string
+		want     []Segment
+	}{
+		{
+			name:     "line and block comments",
+			source:   "a = 1 /* } */ + 2 // }\nb",
+			language: "go",
+			want: []Segment{
+				{Code, "a = 1 ", 0},
+				{Comment, "/* } */", 6},
+				{Code, " + 2 ", 13},
+				{Comment, "// }", 18},
+				{Code, "\nb", 22},
+			},
+		},
+		{
+			name:     "character literal",
+			source:   "char c = '}'; // x",
+			language: "c",
+			want: []Segment{
+				{Code, "char c = ", 0},
+				{String, "'}'", 9},
+				{Code, "; ", 12},
+				{Comment, "// x", 14},
+			},
+		},
+		{
+			name:     "escaped quote",
+			source:   `s := "a\"}" + x`,
+			language: "go",
+			want: []Segment{
+				{Code, "s := ", 0},
+				{String, `"a\"}"`, 5},
+				{Code, " + x", 11},
+			},
+		},
+		{
+			name:     "python triple quotes and hash comments",
+			source:   "s = '''doc\n}''' # c\nt = \"}\"",
+			language: "python",
+			want: []Segment{
+				{Code, "s = ", 0},
+				{String, "'''doc\n}'''", 4},
+				{Code, " ", 15},
+				{Comment, "# c", 16},
+				{Code, "\nt = ", 19},
+				{String, `"}"`, 24},
+			},
+		},
+		{
+			name:     "regex after an operator, division after a number",
+			source:   "a = 1 / 2; b = /}/g.test(s) // c }\n",
+			language: "javascript",
+			want: []Segment{
+				{Code, "a = 1 / 2; b = ", 0},
+				{String, "/}/g", 15},
+				{Code, ".test(s) ", 19},
+				{Comment, "// c }", 28},
+				{Code, "\n", 34},
+			},
+		},
+		{
+			name:     "regex after a keyword with a slash in a class",
+			source:   "return /a[/]b/i",
+			language: "javascript",
+			want: []Segment{
+				{Code, "return ", 0},
+				{String, "/a[/]b/i", 7},
+			},
+		},
+		{
+			name:     "template literal",
+			source:   "x = `a ${b} }` + '}' /* } */",
+			language: "typescript",
+			want: []Segment{
+				{Code, "x = ", 0},
+				{String, "`a ${b} }`", 4},
+				{Code, " + ", 14},
+				{String, "'}'", 17},
+				{Code, " ", 20},
+				{Comment, "/* } */", 21},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Split(tt.source, tt.language); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchBrace(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		language string
+		want     string
+	}{
+		{"nested", "f() { if x { y() } }", "go", "f() { if x { y() } }"},
+		{"brace in string", `f() { s := "}" }`, "go", `f() { s := "}" }`},
+		{"brace in comment", "f() { // }\n}", "c", "f() { // }\n}"},
+		{"brace in regex", "f() { r = /}/ }", "javascript", "f() { r = /}/ }"},
+		{"brace in template", "f() { t = `}` }", "typescript", "f() { t = `}` }"},
+		{"unclosed", "f() { g() ", "go", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments := Split(tt.source, tt.language)
+			open := Index(segments, 0, '{')
+			if open < 0 {
+				t.Fatalf("Index() found no opening brace in %q", tt.source)
+			}
+			got := ""
+			if end := MatchBrace(segments, open); end >= 0 {
+				got = tt.source[:end+1]
+			}
+			if got != tt.want {
+				t.Errorf("MatchBrace() closes at %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	source := `a("{", /* { */ b) { c {`
+	segments := Split(source, "go")
+	want := strings.LastIndex(source, ") {") + 2
+	if got := Index(segments, 0, '{'); got != want {
+		t.Errorf("Index() = %d, want %d", got, want)
+	}
+	if got := Index(segments, want+1, '{'); got != len(source)-1 {
+		t.Errorf("Index() from %d = %d, want %d", want+1, got, len(source)-1)
+	}
+	if got := Index(segments, 0, ';'); got != -1 {
+		t.Errorf("Index() of a missing byte = %d, want -1", got)
+	}
+}
@@ -1,20 +1,31 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
+	"reviewer-bot/audit"
+	"reviewer-bot/cache"
+	"reviewer-bot/config"
+	"reviewer-bot/redact"
 	"reviewer-bot/review"
 	"reviewer-bot/types"
-	"strings"
+	"reviewer-bot/usage"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 const (
 	defaultStyle = "funny"
+	// exitBudgetExhausted is the exit code when functions were skipped for the budget
+	exitBudgetExhausted = 3
 )
 
 func main() {
@@ -24,12 +35,30 @@ func main() {
 		log.Printf("No .env file found, using environment variables")
 	}
 
+	noCache := flag.Bool("no-cache", false, "bypass the review cache")
+	flag.Parse()
+
+	switch flag.Arg(0) {
+	case "cache":
+		runCacheCommand(flag.Args()[1:])
+		return
+	case "usage":
+		runUsageCommand(flag.Args()[1:])
+		return
+	case "dry-run":
+		runDryRun(flag.Args()[1:])
+		return
+	case "audit":
+		runAuditCommand(flag.Args()[1:])
+		return
+	}
+
 	// Only process stdin - no server mode
-	processStdin()
+	processStdin(*noCache)
 }
 
 // processStdin processes requests from stdin (for extension communication)
-func processStdin() {
+func processStdin(noCache bool) {
 	// Read input from stdin
 	input, err := io.ReadAll(os.Stdin)
 	if err != nil {
@@ -52,23 +81,82 @@ func processStdin() {
 		request.Style = defaultStyle
 	}
 
-	// Get API key from environment if not provided in request
-	apiKey := request.APIKey
-	if apiKey == "" {
-		apiKey = os.Getenv("GEMINI_API_KEY")
+	// Load backend configuration, preferring the API key from the request
+	cfg := config.Load()
+	if request.APIKey != "" {
+		cfg.APIKey = request.APIKey
+	}
+	if request.Generation != nil {
+		cfg.Request = *request.Generation
+	}
+
+	// Select the review provider
+	reviewProvider, err := review.NewProvider(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create review provider: %v", err)
 	}
 
-	// Check if we should use mock mode
-	if strings.ToLower(os.Getenv("MOCK_MODE")) == "true" {
-		os.Setenv("MOCK_MODE", "true")
+	// Cancel on SIGINT/SIGTERM and bound the whole run; finished reviews are still printed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	// Open the review cache unless it is bypassed; a broken cache only costs API calls
+	var reviewCache *cache.Cache
+	if !noCache && !request.NoCache && !cfg.Cache.Disabled {
+		reviewCache, err = cache.Open(cfg.Cache.Dir, cfg.Cache.TTL, cfg.Cache.MaxEntries)
+		if err != nil {
+			log.Printf("Review cache disabled: %v", err)
+		}
 	}
 
+	// Work out what is left of the spending budget from the ledger
+	ledger := usage.NewLedger(cfg.LedgerPath)
+	repo := usage.RepoRoot(request.FilePath)
+	var budget *usage.Tracker
+	if cfg.Budget.Enabled() {
+		entries, err := ledger.Entries()
+		if err != nil {
+			log.Fatalf("Failed to read usage ledger: %v", err)
+		}
+		budget = cfg.Budget.Tracker(entries, repo, time.Now())
+	}
+
+	// Attribute audit log entries to the file within its repository
+	ctx = audit.WithFile(ctx, usage.RepoRelative(repo, request.FilePath))
+
 	// Generate reviews
-	generator := review.NewGenerator(apiKey)
-	response, err := generator.GenerateReviews(request.FilePath, request.FileContent, request.Style)
+	options := generatorOptions(cfg)
+	options.Cache = reviewCache
+	options.Budget = budget
+	options.ChangedLines = request.ChangedLines
+	options.Anonymize = options.Anonymize || request.Anonymize
+	generator := review.NewGenerator(reviewProvider, options)
+	response, err := generator.GenerateReviews(ctx, request.FilePath, request.FileContent, request.Style)
 	if err != nil {
 		log.Fatalf("Failed to generate reviews: %v", err)
 	}
+	if reviewCache != nil {
+		if err := reviewCache.Save(); err != nil {
+			log.Printf("Failed to save review cache: %v", err)
+		}
+	}
+	if response.Usage != nil {
+		entry := usage.Entry{
+			Time:  time.Now(),
+			Repo:  repo,
+			File:  request.FilePath,
+			Model: response.Model,
+			Usage: *response.Usage,
+		}
+		if err := ledger.Append(entry); err != nil {
+			log.Printf("Failed to record usage: %v", err)
+		}
+	}
+	if response.Partial {
+		log.Printf("Review run stopped early (%v), returning %d finished reviews", ctx.Err(), len(response.Reviews))
+	}
 
 	// Output response as JSON
 	output, err := json.MarshalIndent(response, "", "  ")
@@ -77,4 +165,160 @@ func processStdin() {
 	}
 
 	fmt.Println(string(output))
+
+	if response.BudgetExhausted {
+		log.Printf("Spending budget exhausted, some functions were not reviewed")
+		os.Exit(exitBudgetExhausted)
+	}
+}
+
+// generatorOptions returns the generator options taken from the configuration
+func generatorOptions(cfg *config.Config) review.Options {
+	options := review.Options{
+		Workers:           cfg.Workers,
+		BatchTokenBudget:  cfg.BatchTokenBudget,
+		MaxFunctionTokens: cfg.MaxFunctionTokens,
+		BatchWorkers:      cfg.BatchWorkers,
+		Prices:            cfg.Prices,
+		Anonymize:         cfg.Anonymize,
+	}
+
+	if !cfg.DisableRedaction {
+		redactor, err := redact.New(cfg.RedactPatterns)
+		if err != nil {
+			// Never fall back to sending unredacted code
+			log.Fatalf("Failed to set up redaction: %v", err)
+		}
+		options.Redactor = redactor
+	}
+	return options
+}
+
+// runDryRun prints every prompt a review of the given file would send, with
+// token and cost estimates, without calling the provider
+func runDryRun(args []string) {
+	flags := flag.NewFlagSet("dry-run", flag.ExitOnError)
+	style := flags.String("style", defaultStyle, "review style")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: reviewer-bot dry-run [-style STYLE] FILE")
+	}
+
+	filePath := flags.Arg(0)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", filePath, err)
+	}
+
+	// Creating a provider makes no network calls; clients connect on first use
+	cfg := config.Load()
+	reviewProvider, err := review.NewProvider(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create review provider: %v", err)
+	}
+
+	generator := review.NewGenerator(reviewProvider, generatorOptions(cfg))
+	generator.Plan(filePath, string(content), *style).Write(os.Stdout)
+}
+
+// runCacheCommand handles the "cache stats|prune|clear" subcommands
+func runCacheCommand(args []string) {
+	if len(args) != 1 {
+		log.Fatal("Usage: reviewer-bot cache stats|prune|clear")
+	}
+
+	cfg := config.Load()
+	reviewCache, err := cache.Open(cfg.Cache.Dir, cfg.Cache.TTL, cfg.Cache.MaxEntries)
+	if err != nil {
+		log.Fatalf("Failed to open review cache: %v", err)
+	}
+
+	switch args[0] {
+	case "stats":
+		output, err := json.MarshalIndent(reviewCache.Stats(), "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to marshal cache stats: %v", err)
+		}
+		fmt.Println(string(output))
+	case "prune":
+		removed, err := reviewCache.Prune()
+		if err != nil {
+			log.Fatalf("Failed to prune review cache: %v", err)
+		}
+		fmt.Printf("Removed %d cached reviews\n", removed)
+	case "clear":
+		if err := reviewCache.Clear(); err != nil {
+			log.Fatalf("Failed to clear review cache: %v", err)
+		}
+		fmt.Println("Review cache cleared")
+	default:
+		log.Fatalf("Unknown cache command %q, expected stats, prune or clear", args[0])
+	}
+}
+
+// runUsageCommand prints the recorded spend per month and repository,
+// optionally limited to one month given as YYYY-MM
+func runUsageCommand(args []string) {
+	if len(args) > 1 {
+		log.Fatal("Usage: reviewer-bot usage [YYYY-MM]")
+	}
+	month := ""
+	if len(args) == 1 {
+		if _, err := time.Parse("2006-01", args[0]); err != nil {
+			log.Fatalf("Invalid month %q, expected YYYY-MM", args[0])
+		}
+		month = args[0]
+	}
+
+	cfg := config.Load()
+	entries, err := usage.NewLedger(cfg.LedgerPath).Entries()
+	if err != nil {
+		log.Fatalf("Failed to read usage ledger: %v", err)
+	}
+
+	output, err := json.MarshalIndent(usage.Summarize(entries, month), "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal usage report: %v", err)
+	}
+	fmt.Println(string(output))
+}
+
+// runAuditCommand prints the audit log entries matching the given date range
+// and file as JSON lines
+func runAuditCommand(args []string) {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	since := flags.String("since", "", "first day to include, YYYY-MM-DD")
+	until := flags.String("until", "", "last day to include, YYYY-MM-DD")
+	file := flags.String("file", "", "only entries whose file path contains this")
+	flags.Parse(args)
+
+	filter := audit.Filter{File: *file}
+	if *since != "" {
+		day, err := time.ParseInLocation("2006-01-02", *since, time.Local)
+		if err != nil {
+			log.Fatalf("Invalid -since date %q, expected YYYY-MM-DD", *since)
+		}
+		filter.Since = day
+	}
+	if *until != "" {
+		day, err := time.ParseInLocation("2006-01-02", *until, time.Local)
+		if err != nil {
+			log.Fatalf("Invalid -until date %q, expected YYYY-MM-DD", *until)
+		}
+		filter.Until = day.AddDate(0, 0, 1)
+	}
+
+	cfg := config.Load()
+	logger := audit.NewLogger(cfg.Audit.Path, cfg.Audit.MaxBytes, cfg.Audit.MaxFiles)
+	entries, err := logger.Query(filter)
+	if err != nil {
+		log.Fatalf("Failed to query audit log: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			log.Fatalf("Failed to write audit entry: %v", err)
+		}
+	}
 }
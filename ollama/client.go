@@ -0,0 +1,296 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reviewer-bot/prompt"
+	"reviewer-bot/provider"
+	"reviewer-bot/types"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHost is the Ollama server used when none is configured
+	DefaultHost = "http://localhost:11434"
+	// DefaultModel is the model used when none is configured
+	DefaultModel = "llama3.2"
+	// DefaultTimeout is the request timeout used when none is configured
+	DefaultTimeout = 120 * time.Second
+)
+
+// Config holds the settings for an Ollama server
+type Config struct {
+	// Host is the Ollama server URL
+	Host string
+	// Model is the local model name, e.g. "llama3.2" or "qwen2.5-coder:7b"
+	Model string
+	// Timeout bounds each request including reading the reply
+	Timeout time.Duration
+	// DisableStreaming requests the whole reply in a single response instead of
+	// reading it in chunks as it is generated
+	DisableStreaming bool
+}
+
+// Client talks to a local Ollama server
+type Client struct {
+	config     Config
+	httpClient *http.Client
+
+	mu           sync.Mutex
+	modelChecked bool
+}
+
+// chatMessage is a single message in a chat request
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the body of an /api/chat request
+type chatRequest struct {
+	Model    string         `json:"model"`
+	Messages []chatMessage  `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Format   map[string]any `json:"format,omitempty"`
+}
+
+// chatChunk is a full response or a single streamed chunk from /api/chat
+type chatChunk struct {
+	Message chatMessage `json:"message"`
+	Done    bool        `json:"done"`
+	Error   string      `json:"error,omitempty"`
+	// PromptEvalCount and EvalCount are the token counts sent with the final chunk
+	PromptEvalCount int `json:"prompt_eval_count,omitempty"`
+	EvalCount       int `json:"eval_count,omitempty"`
+}
+
+// tagsResponse is the body of an /api/tags response
+type tagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+}
+
+// NewClient creates a new Ollama client
+func NewClient(config Config) *Client {
+	if config.Host == "" {
+		config.Host = DefaultHost
+	}
+	config.Host = strings.TrimRight(config.Host, "/")
+	if config.Model == "" {
+		config.Model = DefaultModel
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	return &Client{
+		config:     config,
+		httpClient: &http.Client{},
+	}
+}
+
+// Capabilities reports which features the client supports
+func (c *Client) Capabilities() provider.Capabilities {
+	return provider.Capabilities{Batch: true}
+}
+
+// ModelName returns the configured model name
+func (c *Client) ModelName(style string) string {
+	return c.config.Model
+}
+
+// GenerateReview generates a review for a function
+func (c *Client) GenerateReview(ctx context.Context, functionName, functionCode, style string) (provider.Response, error) {
+	return c.chat(ctx, prompt.GetReviewPrompt(style, functionName, functionCode), nil)
+}
+
+// GenerateBatchReview generates reviews for multiple functions in a single API call
+func (c *Client) GenerateBatchReview(ctx context.Context, items []provider.BatchItem, style string) (provider.Response, error) {
+	return c.chat(ctx, prompt.GetBatchPrompt(items, style), prompt.BatchResponseSchema())
+}
+
+// CheckModel verifies that the configured model has been pulled locally
+func (c *Client) CheckModel(ctx context.Context) error {
+	callCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(callCtx, http.MethodGet, c.config.Host+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return provider.RequestError(ctx, callCtx, "Ollama API", c.config.Timeout, fmt.Errorf("Ollama is not reachable at %s. Is `ollama serve` running? Error: %v", c.config.Host, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return mapError(resp.StatusCode, strings.TrimSpace(string(data)), provider.ParseRetryAfter(resp.Header.Get("Retry-After")))
+	}
+
+	var tags tagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return fmt.Errorf("failed to parse Ollama model list: %v", err)
+	}
+
+	for _, model := range tags.Models {
+		if matchesModel(model.Name, c.config.Model) || matchesModel(model.Model, c.config.Model) {
+			return nil
+		}
+	}
+
+	return &provider.Error{
+		Kind: provider.ErrInvalidRequest,
+		Err:  fmt.Errorf("Ollama model %q is not available locally. Run `ollama pull %s` first", c.config.Model, c.config.Model),
+	}
+}
+
+// ensureModel checks the configured model once, until the check succeeds
+func (c *Client) ensureModel(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.modelChecked {
+		return nil
+	}
+	if err := c.CheckModel(ctx); err != nil {
+		return err
+	}
+	c.modelChecked = true
+	return nil
+}
+
+// matchesModel reports whether a local model name refers to the wanted model,
+// treating a missing tag as ":latest"
+func matchesModel(local, wanted string) bool {
+	if local == wanted {
+		return true
+	}
+	if !strings.Contains(wanted, ":") {
+		return local == wanted+":latest"
+	}
+	return false
+}
+
+// chat sends a chat request with the prompt's instructions as the system message
+// and returns the reply text, constraining it to the given JSON schema when one is set
+func (c *Client) chat(ctx context.Context, reviewPrompt prompt.Prompt, format map[string]any) (provider.Response, error) {
+	if err := c.ensureModel(ctx); err != nil {
+		return provider.Response{}, err
+	}
+
+	body, err := json.Marshal(chatRequest{
+		Model: c.config.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: reviewPrompt.System},
+			{Role: "user", Content: reviewPrompt.User},
+		},
+		Stream: !c.config.DisableStreaming,
+		Format: format,
+	})
+	if err != nil {
+		return provider.Response{}, fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(callCtx, http.MethodPost, c.config.Host+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return provider.Response{}, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return provider.Response{}, provider.RequestError(ctx, callCtx, "Ollama API", c.config.Timeout, fmt.Errorf("Ollama API error: %v", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		var chunk chatChunk
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &chunk) == nil && chunk.Error != "" {
+			message = chunk.Error
+		}
+		return provider.Response{}, mapError(resp.StatusCode, message, provider.ParseRetryAfter(resp.Header.Get("Retry-After")))
+	}
+
+	var reply strings.Builder
+	var usage types.Usage
+	done := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return provider.Response{}, fmt.Errorf("failed to parse Ollama response: %v", err)
+		}
+		if chunk.Error != "" {
+			return provider.Response{}, mapError(http.StatusInternalServerError, chunk.Error, 0)
+		}
+
+		reply.WriteString(chunk.Message.Content)
+		if chunk.Done {
+			done = true
+			usage.PromptTokens = chunk.PromptEvalCount
+			usage.ResponseTokens = chunk.EvalCount
+			usage.TotalTokens = chunk.PromptEvalCount + chunk.EvalCount
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return provider.Response{}, provider.RequestError(ctx, callCtx, "Ollama API", c.config.Timeout, fmt.Errorf("Ollama API error: %v", err))
+	}
+	if !done {
+		// The connection closed before the final chunk, the reply may be cut short
+		return provider.Response{}, &provider.Error{Kind: provider.ErrTransient, Err: fmt.Errorf("Ollama reply ended before it was complete")}
+	}
+
+	if strings.TrimSpace(reply.String()) == "" {
+		return provider.Response{}, &provider.Error{Kind: provider.ErrTransient, Err: fmt.Errorf("no response from Ollama API")}
+	}
+
+	return provider.Response{Text: strings.TrimSpace(reply.String()), Usage: usage}, nil
+}
+
+// mapError converts an Ollama error into the same messages and error kinds the
+// Gemini client produces, so the extension can show the same hints
+func mapError(status int, message string, retryAfter time.Duration) error {
+	kind := provider.KindForStatus(status)
+	if status == http.StatusServiceUnavailable {
+		// Ollama answers 503 when its request queue is full
+		kind = provider.ErrQuota
+	}
+	providerErr := &provider.Error{Kind: kind, StatusCode: status, RetryAfter: retryAfter}
+
+	switch {
+	case kind == provider.ErrQuota:
+		providerErr.Err = fmt.Errorf("API quota exceeded. Ollama is busy or overloaded, try again later. Error: %s", message)
+	case kind == provider.ErrAuth:
+		providerErr.Err = fmt.Errorf("Invalid API key. Ollama rejected the request. Error: %s", message)
+	case status == http.StatusNotFound || strings.Contains(message, "not found"):
+		providerErr.Kind = provider.ErrInvalidRequest
+		providerErr.Err = fmt.Errorf("Ollama model not found. Run `ollama pull` for the configured model. Error: %s", message)
+	default:
+		providerErr.Err = fmt.Errorf("Ollama API error (%d): %s", status, message)
+	}
+	return providerErr
+}
@@ -0,0 +1,244 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reviewer-bot/prompt"
+	"reviewer-bot/provider"
+	"reviewer-bot/types"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the base URL used when none is configured
+	DefaultBaseURL = "http://localhost:8080/v1"
+	// DefaultTimeout is the request timeout used when none is configured
+	DefaultTimeout = 60 * time.Second
+)
+
+// Config holds the settings for an OpenAI-compatible server
+type Config struct {
+	// BaseURL is the API root, e.g. "http://localhost:8080/v1"
+	BaseURL string
+	// APIKey is sent in the auth header when set
+	APIKey string
+	// Model is the model name passed in each request
+	Model string
+	// Timeout bounds each HTTP request
+	Timeout time.Duration
+	// AuthHeader is the header carrying the API key (default "Authorization")
+	AuthHeader string
+	// AuthScheme prefixes the API key in the auth header (default "Bearer")
+	AuthScheme string
+	// DisableJSONSchema omits response_format for servers that reject it
+	DisableJSONSchema bool
+}
+
+// Client talks to any server implementing the /chat/completions endpoint
+type Client struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// chatMessage is a single message in a chat completion request
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the body of a chat completion request
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+// responseFormat constrains the reply to a JSON schema
+type responseFormat struct {
+	Type       string     `json:"type"`
+	JSONSchema jsonSchema `json:"json_schema"`
+}
+
+// jsonSchema is a named JSON schema for structured output
+type jsonSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+}
+
+// chatResponse is the body of a chat completion response
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// NewClient creates a new OpenAI-compatible client
+func NewClient(config Config) *Client {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultBaseURL
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.AuthHeader == "" {
+		config.AuthHeader = "Authorization"
+		if config.AuthScheme == "" {
+			config.AuthScheme = "Bearer"
+		}
+	}
+
+	return &Client{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Capabilities reports which features the client supports
+func (c *Client) Capabilities() provider.Capabilities {
+	return provider.Capabilities{Batch: true}
+}
+
+// ModelName returns the configured model name
+func (c *Client) ModelName(style string) string {
+	return c.config.Model
+}
+
+// GenerateReview generates a review for a function
+func (c *Client) GenerateReview(ctx context.Context, functionName, functionCode, style string) (provider.Response, error) {
+	return c.complete(ctx, prompt.GetReviewPrompt(style, functionName, functionCode), nil)
+}
+
+// GenerateBatchReview generates reviews for multiple functions in a single API call
+func (c *Client) GenerateBatchReview(ctx context.Context, items []provider.BatchItem, style string) (provider.Response, error) {
+	if c.config.DisableJSONSchema {
+		return c.complete(ctx, prompt.GetBatchPrompt(items, style), nil)
+	}
+
+	// Structured outputs need an object at the root, so the array is wrapped
+	format := &responseFormat{
+		Type: "json_schema",
+		JSONSchema: jsonSchema{Name: "batch_reviews", Schema: map[string]any{
+			"type":                 "object",
+			"properties":           map[string]any{"reviews": prompt.BatchResponseSchema()},
+			"required":             []string{"reviews"},
+			"additionalProperties": false,
+		}},
+	}
+	response, err := c.complete(ctx, prompt.GetBatchPrompt(items, style), format)
+	if err != nil {
+		return response, err
+	}
+	response.Text = unwrapReviews(response.Text)
+	return response, nil
+}
+
+// unwrapReviews returns the array of a {"reviews": [...]} reply, or the reply as
+// is when it has another shape, leaving the caller to report it
+func unwrapReviews(text string) string {
+	var wrapped struct {
+		Reviews json.RawMessage `json:"reviews"`
+	}
+	if err := json.Unmarshal([]byte(text), &wrapped); err != nil || len(wrapped.Reviews) == 0 {
+		return text
+	}
+	return string(wrapped.Reviews)
+}
+
+// complete sends a chat completion request with the prompt's instructions as the
+// system message and returns the reply text
+func (c *Client) complete(ctx context.Context, reviewPrompt prompt.Prompt, format *responseFormat) (provider.Response, error) {
+	body, err := json.Marshal(chatRequest{
+		Model: c.config.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: reviewPrompt.System},
+			{Role: "user", Content: reviewPrompt.User},
+		},
+		ResponseFormat: format,
+	})
+	if err != nil {
+		return provider.Response{}, fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(callCtx, http.MethodPost, c.config.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return provider.Response{}, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		value := c.config.APIKey
+		if c.config.AuthScheme != "" {
+			value = c.config.AuthScheme + " " + value
+		}
+		req.Header.Set(c.config.AuthHeader, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return provider.Response{}, provider.RequestError(ctx, callCtx, "OpenAI-compatible API", c.config.Timeout, fmt.Errorf("OpenAI-compatible API error: %v", err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return provider.Response{}, provider.RequestError(ctx, callCtx, "OpenAI-compatible API", c.config.Timeout, fmt.Errorf("failed to read response: %v", err))
+	}
+
+	var result chatResponse
+	if err := json.Unmarshal(data, &result); err != nil && resp.StatusCode == http.StatusOK {
+		return provider.Response{}, &provider.Error{Kind: provider.ErrTransient, Err: fmt.Errorf("failed to parse response: %v", err)}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		message := strings.TrimSpace(string(data))
+		if result.Error != nil && result.Error.Message != "" {
+			message = result.Error.Message
+		}
+
+		kind := provider.KindForStatus(resp.StatusCode)
+		providerErr := &provider.Error{
+			Kind:       kind,
+			StatusCode: resp.StatusCode,
+			RetryAfter: provider.ParseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+		switch kind {
+		case provider.ErrQuota:
+			providerErr.Err = fmt.Errorf("API quota exceeded. Please check your plan or try again later. Error: %s", message)
+		case provider.ErrAuth:
+			providerErr.Err = fmt.Errorf("Invalid API key. Please check your API key. Error: %s", message)
+		default:
+			providerErr.Err = fmt.Errorf("OpenAI-compatible API error (%d): %s", resp.StatusCode, message)
+		}
+		return provider.Response{}, providerErr
+	}
+
+	if len(result.Choices) == 0 || strings.TrimSpace(result.Choices[0].Message.Content) == "" {
+		return provider.Response{}, &provider.Error{Kind: provider.ErrTransient, Err: fmt.Errorf("no response from OpenAI-compatible API")}
+	}
+
+	response := provider.Response{Text: strings.TrimSpace(result.Choices[0].Message.Content)}
+	if result.Usage != nil {
+		response.Usage = types.Usage{
+			PromptTokens:   result.Usage.PromptTokens,
+			ResponseTokens: result.Usage.CompletionTokens,
+			TotalTokens:    result.Usage.PromptTokens + result.Usage.CompletionTokens,
+		}
+	}
+	return response, nil
+}
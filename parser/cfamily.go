@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"reviewer-bot/types"
+	"strings"
+)
+
+// classKeywords introduce class-like declarations
+var classKeywords = map[string]bool{
+	"class": true, "struct": true, "union": true, "interface": true, "enum": true, "record": true,
+}
+
+// notFunctionNames are words that can be followed by a parenthesis without
+// naming a function
+var notFunctionNames = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true,
+	"sizeof": true, "alignof": true, "alignas": true, "decltype": true, "typeof": true,
+	"__attribute__": true, "__declspec": true, "static_assert": true, "throw": true,
+	"noexcept": true, "new": true, "delete": true, "synchronized": true, "requires": true,
+}
+
+// trailerWords may follow the parameter list of a function definition
+var trailerWords = map[string]bool{
+	"const": true, "volatile": true, "noexcept": true, "throw": true, "throws": true,
+	"override": true, "final": true, "requires": true, "try": true, "__attribute__": true,
+}
+
+// ParseFunctions parses C functions
+func (p *CParser) ParseFunctions(content string) []types.FunctionInfo {
+	return scanDeclarations(content, cFamilyGrammar("c"))
+}
+
+// ParseFunctions parses C++ functions, including out-of-line members such as
+// Foo::bar, constructors, destructors, operators and templates
+func (p *CppParser) ParseFunctions(content string) []types.FunctionInfo {
+	return scanDeclarations(content, cFamilyGrammar("cpp"))
+}
+
+// ParseFunctions parses Java methods and constructors
+func (p *JavaParser) ParseFunctions(content string) []types.FunctionInfo {
+	return scanDeclarations(content, cFamilyGrammar("java"))
+}
+
+// cFamilyGrammar returns the declaration rules of C, C++ or Java
+func cFamilyGrammar(language string) grammar {
+	return grammar{
+		language:     language,
+		preprocessor: language != "java",
+		parse: func(header string, inClass bool) declaration {
+			return parseCDeclaration(header, language)
+		},
+		extendsHeader: func(header string, decl declaration) bool {
+			// A member initialized with braces, as in Foo() : items{} {
+			return decl.kind == functionDeclaration && decl.initList && endsWithName(header)
+		},
+	}
+}
+
+// parseCDeclaration works out what a C, C++ or Java header declares
+func parseCDeclaration(header, language string) declaration {
+	decl := declaration{start: skipAnnotations(header)}
+	text := header[decl.start:]
+
+	classKeyword := -1
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case isSpaceByte(c):
+			i++
+		case c == '<':
+			i = skipAngles(text, i)
+		case c == '(':
+			i = skipBalanced(text, i, '(', ')')
+		case c == '[':
+			i = skipBalanced(text, i, '[', ']')
+		case c == '=':
+			// An assignment such as a field initialized with a lambda or an array
+			return declaration{}
+		case c == '@' && strings.HasPrefix(text[i:], "@interface"):
+			decl.kind, decl.keyword = classDeclaration, "interface"
+			decl.name = className(text[i+len("@interface"):])
+			return decl
+		case c == '~' || c == ':' || isIdentByte(c):
+			qualifier, name, end := readName(text, i)
+			if name == "" {
+				i = end + 1
+				continue
+			}
+			next := skipSpace(text, end)
+			switch {
+			case name == "namespace":
+				decl.kind = namespaceDeclaration
+				return decl
+			case name == "record" && language == "java" && next < len(text) && isIdentByte(text[next]):
+				// A record names its components, as in record Point(int x, int y)
+				decl.kind, decl.keyword = classDeclaration, name
+				decl.name = className(text[end:])
+				return decl
+			case classKeywords[name] && classKeyword < 0:
+				classKeyword = i
+			}
+
+			if next < len(text) && text[next] == '(' && !notFunctionNames[name] {
+				closing := skipBalanced(text, next, '(', ')')
+				if trailer := strings.TrimSpace(text[closing:]); validTrailer(trailer) {
+					decl.kind, decl.name, decl.qualifier = functionDeclaration, name, qualifier
+					decl.initList = strings.HasPrefix(trailer, ":")
+					return decl
+				}
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+
+	switch {
+	case classKeyword >= 0:
+		_, keyword, end := readName(text, classKeyword)
+		decl.kind, decl.keyword = classDeclaration, keyword
+		decl.name = className(text[end:])
+	case hasWord(text, "extern"):
+		// extern "C" { ... }
+		decl.kind = namespaceDeclaration
+	}
+	return decl
+}
+
+// validTrailer reports whether the text after a parameter list can end the
+// signature of a function definition
+func validTrailer(trailer string) bool {
+	switch {
+	case trailer == "":
+		return true
+	case strings.HasPrefix(trailer, ":") && !strings.HasPrefix(trailer, "::"):
+		// A constructor's member initializer list
+		return true
+	case strings.HasPrefix(trailer, "->"), strings.HasPrefix(trailer, "&"), strings.HasPrefix(trailer, "[["):
+		return true
+	}
+	end := 0
+	for end < len(trailer) && isIdentByte(trailer[end]) {
+		end++
+	}
+	return trailerWords[trailer[:end]]
+}
+
+// readName reads a possibly qualified name such as std::vector<T>::push_back,
+// ~Foo or operator==, returning the class qualifying it, if any, the name itself
+// and the index just past it
+func readName(text string, i int) (qualifier, name string, end int) {
+	var parts []string
+	for {
+		if strings.HasPrefix(text[i:], "::") {
+			i = skipSpace(text, i+2)
+		}
+		start := i
+		if i < len(text) && text[i] == '~' {
+			i = skipSpace(text, i+1)
+		}
+		for i < len(text) && isIdentByte(text[i]) {
+			i++
+		}
+		word := strings.Join(strings.Fields(text[start:i]), "")
+		if word == "" {
+			break
+		}
+		if word == "operator" {
+			i = operatorEnd(text, i)
+			word = operatorName(text[start:i])
+		}
+		parts = append(parts, word)
+
+		// Template arguments are part of a qualifier, as in Foo<T>::bar
+		next := skipSpace(text, i)
+		if next < len(text) && text[next] == '<' && !strings.HasPrefix(word, "operator") {
+			if after := skipSpace(text, skipAngles(text, next)); strings.HasPrefix(text[after:], "::") {
+				next = after
+			}
+		}
+		if !strings.HasPrefix(text[next:], "::") {
+			break
+		}
+		i = next
+	}
+
+	if len(parts) == 0 {
+		return "", "", i
+	}
+	if len(parts) > 1 {
+		qualifier = parts[len(parts)-2]
+	}
+	return qualifier, parts[len(parts)-1], i
+}
+
+// operatorEnd returns the index of the parameter list following the operator
+// keyword at i, as in operator() or operator new[]
+func operatorEnd(text string, i int) int {
+	i = skipSpace(text, i)
+	if strings.HasPrefix(text[i:], "()") {
+		i += 2
+	}
+	if index := strings.IndexByte(text[i:], '('); index >= 0 {
+		return i + index
+	}
+	return len(text)
+}
+
+// operatorName writes an operator's name without spaces, keeping a single space
+// in front of a word, as in operator== and operator bool
+func operatorName(text string) string {
+	rest := strings.Join(strings.Fields(strings.TrimPrefix(text, "operator")), " ")
+	if rest != "" && isIdentByte(rest[0]) {
+		return "operator " + rest
+	}
+	return "operator" + rest
+}
+
+// endsWithName reports whether a header ends with an identifier or template
+// arguments, as a member initialized with braces does
+func endsWithName(header string) bool {
+	header = strings.TrimRight(header, " \t\r\n")
+	if header == "" {
+		return false
+	}
+	last := header[len(header)-1]
+	return isIdentByte(last) || last == '>'
+}
+
+// hasWord reports whether text contains word as a whole identifier
+func hasWord(text, word string) bool {
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r > 0x7f || !isIdentByte(byte(r)) }) {
+		if field == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCFamilyParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    []wantFunction
+	}{
+		{
+			name: "java methods and constructors",
+			path: "Main.java",
+			content: `package demo;
+
+public class Main {
+    @Override
+    public String toString() {
+        return "}";
+    }
+
+    public Main(int size) {
+        this.size = size;
+    }
+
+    public static void main(String[] args) {
+        System.out.println("{");
+    }
+
+    protected <T extends Comparable<T>> List<T> sort(List<T> items)
+            throws IllegalStateException {
+        return items;
+    }
+
+    abstract void draw();
+}
+`,
+			want: []wantFunction{
+				{id: "toString@L5", endLine: 7, class: "Main"},
+				{id: "Main@L9", endLine: 11, class: "Main"},
+				{id: "main@L13", endLine: 15, class: "Main"},
+				{id: "sort@L17", endLine: 20, class: "Main"},
+			},
+		},
+		{
+			name: "java interface default methods",
+			path: "Shape.java",
+			content: `interface Shape {
+    double area();
+
+    default String name() {
+        return "shape";
+    }
+}
+`,
+			want: []wantFunction{
+				{id: "name@L4", endLine: 6, class: "Shape"},
+			},
+		},
+		{
+			name: "c split signatures and allman braces",
+			path: "list.c",
+			content: `#include <stdio.h>
+
+static inline int
+max_of(int a, int b)
+{
+    return a > b ? a : b;
+}
+
+int count(const char *s);
+
+char close_brace(void) {
+    return '}'; /* { */
+}
+
+struct node *make_node(int value,
+                       struct node *next) {
+    return NULL;
+}
+`,
+			want: []wantFunction{
+				{id: "max_of@L3", endLine: 7},
+				{id: "close_brace@L11", endLine: 13},
+				{id: "make_node@L15", endLine: 18},
+			},
+		},
+		{
+			name: "c++ members, operators and templates",
+			path: "shape.cpp",
+			content: `extern "C" {
+int c_entry(int x) {
+    return x;
+}
+}
+
+class Foo {
+public:
+    Foo() : n(0) {}
+    ~Foo() {}
+    int bar() const {
+        return n;
+    }
+    bool operator==(const Foo& other) const { return n == other.n; }
+private:
+    int n;
+};
+
+int Foo::baz() const {
+    return n;
+}
+
+template <typename T>
+T Box<T>::get() {
+    return value;
+}
+`,
+			want: []wantFunction{
+				{id: "c_entry@L2", endLine: 4},
+				{id: "Foo@L9", endLine: 9, class: "Foo"},
+				{id: "~Foo@L10", endLine: 10, class: "Foo"},
+				{id: "bar@L11", endLine: 13, class: "Foo"},
+				{id: "operator==@L14", endLine: 14, class: "Foo"},
+				{id: "baz@L19", endLine: 21, class: "Foo"},
+				{id: "get@L23", endLine: 26, class: "Box"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkFunctions(t, ParseFile(tt.path, tt.content), tt.want)
+		})
+	}
+}
+
+func TestCFamilyDetails(t *testing.T) {
+	content := "public class Main {\n    public static void main(String[] args) {\n    }\n\n    private int size(int a, int b) {\n        return a;\n    }\n}\n"
+	functions := ParseFile("Main.java", content)
+	if len(functions) != 2 {
+		t.Fatalf("got %d functions, want 2", len(functions))
+	}
+
+	main := functions[0]
+	if main.QualifiedName != "Main.main" || main.Visibility != "public" || !reflect.DeepEqual(main.Params, []string{"String[] args"}) {
+		t.Errorf("main = {%s %s %q}, want {Main.main public [String[] args]}", main.QualifiedName, main.Visibility, main.Params)
+	}
+	size := functions[1]
+	if size.Visibility != "private" || !reflect.DeepEqual(size.Params, []string{"int a", "int b"}) {
+		t.Errorf("size = {%s %q}, want {private [int a int b]}", size.Visibility, size.Params)
+	}
+
+	noargs := ParseFile("main.c", "int noargs(void) {\n    return 0;\n}\n")
+	if len(noargs) != 1 || noargs[0].Params != nil {
+		t.Errorf("noargs(void) params = %v, want none", noargs)
+	}
+}
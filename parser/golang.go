@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"bufio"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"regexp"
+	"reviewer-bot/types"
+	"strings"
+	"unicode/utf8"
+)
+
+// goFuncRegex matches a single-line Go function or method signature; it is only
+// used when the file does not parse
+var goFuncRegex = regexp.MustCompile(`^\s*func\s+(?:\([^)]+\)\s+)?([a-zA-Z_][a-zA-Z0-9_]*)\s*\([^)]*\)\s*(?:[^{]*)?\s*\{`)
+
+// ParseFunctions parses Go functions, methods and function literals assigned to
+// package-level variables with go/parser, falling back to a line scan when the
+// file has syntax errors before its first function
+func (p *GoParser) ParseFunctions(content string) []types.FunctionInfo {
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "", content, goparser.ParseComments|goparser.SkipObjectResolution)
+	if file == nil {
+		return parseGoLines(content)
+	}
+
+	var functions []types.FunctionInfo
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Body == nil {
+				// Declarations without a body are implemented elsewhere, e.g. in assembly
+				continue
+			}
+			function := types.FunctionInfo{
+				Name:       decl.Name.Name,
+				Language:   "go",
+				Signature:  collapseSpace(content[fset.Position(decl.Pos()).Offset:fset.Position(decl.Body.Lbrace).Offset]),
+				Params:     goParams(fset, content, decl.Type.Params),
+				Visibility: goVisibility(decl.Name.Name),
+				Doc:        strings.TrimSpace(decl.Doc.Text()),
+			}
+			setGoRange(&function, fset, content, decl.Pos(), decl.End())
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				function.Receiver = goSource(fset, content, decl.Recv.List[0].Type)
+				function.Class = goTypeName(decl.Recv.List[0].Type)
+			}
+			if decl.Type.TypeParams != nil {
+				function.TypeParams = goSource(fset, content, decl.Type.TypeParams)
+			}
+			function.QualifiedName = qualifiedName(function.Class, function.Name)
+			functions = append(functions, function)
+		case *ast.GenDecl:
+			functions = append(functions, goFuncVars(fset, content, decl)...)
+		}
+	}
+
+	if err != nil && len(functions) == 0 {
+		return parseGoLines(content)
+	}
+	return functions
+}
+
+// goFuncVars returns the function literals assigned in a var declaration, such
+// as var handler = func(w http.ResponseWriter, r *http.Request) {...}
+func goFuncVars(fset *token.FileSet, content string, decl *ast.GenDecl) []types.FunctionInfo {
+	if decl.Tok != token.VAR {
+		return nil
+	}
+
+	var functions []types.FunctionInfo
+	for _, spec := range decl.Specs {
+		valueSpec := spec.(*ast.ValueSpec)
+		for i, value := range valueSpec.Values {
+			literal, ok := value.(*ast.FuncLit)
+			if !ok || i >= len(valueSpec.Names) {
+				continue
+			}
+
+			doc := valueSpec.Doc
+			if doc == nil && len(decl.Specs) == 1 {
+				doc = decl.Doc
+			}
+			name := valueSpec.Names[i].Name
+			function := types.FunctionInfo{
+				Name:          name,
+				Language:      "go",
+				Signature:     collapseSpace(content[fset.Position(literal.Pos()).Offset:fset.Position(literal.Body.Lbrace).Offset]),
+				QualifiedName: name,
+				Params:        goParams(fset, content, literal.Type.Params),
+				Visibility:    goVisibility(name),
+				Doc:           strings.TrimSpace(doc.Text()),
+			}
+			setGoRange(&function, fset, content, valueSpec.Names[i].Pos(), literal.End())
+			functions = append(functions, function)
+		}
+	}
+	return functions
+}
+
+// setGoRange sets the lines and character columns of a function from its first
+// position and the position just after it
+func setGoRange(function *types.FunctionInfo, fset *token.FileSet, content string, start, end token.Pos) {
+	startPosition := fset.Position(start)
+	endPosition := fset.Position(end - 1)
+	function.Line = startPosition.Line
+	function.Column = goColumn(content, startPosition)
+	function.EndLine = endPosition.Line
+	function.EndColumn = goColumn(content, endPosition)
+}
+
+// goColumn converts a position's byte column into a character column
+func goColumn(content string, p token.Position) int {
+	lineStart := p.Offset - (p.Column - 1)
+	return utf8.RuneCountInString(content[lineStart:p.Offset]) + 1
+}
+
+// goParams returns every parameter of a field list as "name type"
+func goParams(fset *token.FileSet, content string, fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+
+	var params []string
+	for _, field := range fields.List {
+		fieldType := goSource(fset, content, field.Type)
+		if len(field.Names) == 0 {
+			params = append(params, fieldType)
+		}
+		for _, name := range field.Names {
+			params = append(params, name.Name+" "+fieldType)
+		}
+	}
+	return params
+}
+
+// goTypeName returns the name of a receiver type without pointer or type arguments
+func goTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return goTypeName(expr.X)
+	case *ast.IndexExpr:
+		return goTypeName(expr.X)
+	case *ast.IndexListExpr:
+		return goTypeName(expr.X)
+	case *ast.ParenExpr:
+		return goTypeName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// goVisibility reports exported names as public
+func goVisibility(name string) string {
+	if ast.IsExported(name) {
+		return types.VisibilityPublic
+	}
+	return types.VisibilityPrivate
+}
+
+// goSource returns the source text of a node
+func goSource(fset *token.FileSet, content string, node ast.Node) string {
+	start := fset.Position(node.Pos()).Offset
+	end := fset.Position(node.End()).Offset
+	if start < 0 || end > len(content) || start > end {
+		return ""
+	}
+	return content[start:end]
+}
+
+// parseGoLines finds Go functions whose signature fits on one line, for files
+// go/parser cannot read
+func parseGoLines(content string) []types.FunctionInfo {
+	var functions []types.FunctionInfo
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNum := 1
+
+	for scanner.Scan() {
+		line := scanner.Text() // Don't trim, keep original indentation
+		if matches := goFuncRegex.FindStringSubmatch(line); matches != nil {
+			functions = append(functions, types.FunctionInfo{
+				Name:     matches[1],
+				Line:     lineNum,
+				Language: "go",
+			})
+		}
+		lineNum++
+	}
+
+	return completeFunctions(content, functions)
+}
@@ -0,0 +1,475 @@
+package parser
+
+import (
+	"regexp"
+	"reviewer-bot/lexer"
+	"reviewer-bot/types"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// classPattern matches class-like declarations in brace-delimited languages
+var classPattern = regexp.MustCompile(`^\s*(?:(?:export|default|abstract|final|sealed|public|private|protected|static|internal)\s+)*(?:class|interface|enum|struct|mixin|extension)\s+([A-Za-z_$][\w$]*)`)
+
+// pythonClassPattern matches a Python class statement
+var pythonClassPattern = regexp.MustCompile(`^\s*class\s+([A-Za-z_]\w*)`)
+
+// source is file content split into lines and lexical segments, used to
+// complete the function information found by the line-based parsers
+type source struct {
+	lines []string
+	// starts holds the byte offset of every line
+	starts   []int
+	segments []lexer.Segment
+	// continued marks the lines starting inside brackets or a multi-line string,
+	// which do not end a Python block whatever their indentation
+	continued []bool
+}
+
+// position is a place in the source as a 0-based line and byte index
+type position struct {
+	line, index int
+}
+
+// class is a class-like declaration and the lines it spans, 1-based
+type class struct {
+	name      string
+	startLine int
+	endLine   int
+}
+
+// newSource splits content into lines and segments with the rules of language
+func newSource(content, language string) *source {
+	s := &source{
+		lines:    strings.Split(content, "\n"),
+		segments: lexer.Split(content, language),
+	}
+	offset := 0
+	for _, line := range s.lines {
+		s.starts = append(s.starts, offset)
+		offset += len(line) + 1
+	}
+	if language == "python" {
+		s.continued = s.continuedLines()
+	}
+	return s
+}
+
+// continuedLines marks the lines that start inside brackets, a string or after
+// a backslash continuation
+func (s *source) continuedLines() []bool {
+	continued := make([]bool, len(s.lines))
+	depth := 0
+	line := 0
+	for _, segment := range s.segments {
+		for i := 0; i < len(segment.Text); i++ {
+			switch c := segment.Text[i]; {
+			case c == '\n':
+				line++
+				continued[line] = segment.Kind != lexer.Code || depth > 0 || (i > 0 && segment.Text[i-1] == '\\')
+			case segment.Kind != lexer.Code:
+			case c == '(' || c == '[' || c == '{':
+				depth++
+			case c == ')' || c == ']' || c == '}':
+				depth = max(depth-1, 0)
+			}
+		}
+	}
+	return continued
+}
+
+// completeFunctions fills in the range, signature, parameters, doc comment, class,
+// qualified name and visibility of functions found by a line-based parser
+func completeFunctions(content string, functions []types.FunctionInfo) []types.FunctionInfo {
+	if len(functions) == 0 {
+		return functions
+	}
+
+	s := newSource(content, functions[0].Language)
+	python := functions[0].Language == "python"
+	classes := s.classes(python)
+	for i := range functions {
+		if python {
+			s.completePython(&functions[i])
+		} else {
+			s.completeBraced(&functions[i])
+		}
+		functions[i].Class = innermostClass(classes, functions[i].Line)
+		functions[i].QualifiedName = qualifiedName(functions[i].Class, functions[i].Name)
+	}
+	return functions
+}
+
+// FunctionEnd returns the 1-based last line of the function starting on a 1-based
+// line, found by indentation for Python and by matching braces outside comments
+// and strings otherwise. A function whose braces are never closed runs to the end
+// of the content.
+func FunctionEnd(content string, line int, language string) int {
+	s := newSource(content, language)
+	if line < 1 || line > len(s.lines) {
+		return line
+	}
+
+	function := types.FunctionInfo{Line: line, Language: language}
+	if language == "python" {
+		s.completePython(&function)
+	} else {
+		s.completeBraced(&function)
+	}
+	return function.EndLine
+}
+
+// completeBraced completes a function whose body is delimited by braces
+func (s *source) completeBraced(function *types.FunctionInfo) {
+	start := position{function.Line - 1, indentWidth(s.lines[function.Line-1])}
+	if open, found := s.find(start, '{'); found {
+		s.completeBody(function, start, open)
+		return
+	}
+
+	function.Column = s.column(start)
+	end := position{start.line, len(s.lines[start.line]) - 1}
+	function.EndLine = end.line + 1
+	function.EndColumn = s.column(end)
+	function.Signature = collapseSpace(s.lines[start.line][start.index:])
+	s.completeDeclaration(function)
+}
+
+// completeBody completes a function from the start of its declaration and the
+// brace opening its body; a body that is never closed runs to the end of the content
+func (s *source) completeBody(function *types.FunctionInfo, start, open position) {
+	function.Column = s.column(start)
+	end, ok := s.matchBrace(open)
+	if !ok {
+		last := len(s.lines) - 1
+		end = position{last, max(len(strings.TrimRight(s.lines[last], " \t\r"))-1, 0)}
+	}
+	function.EndLine = end.line + 1
+	function.EndColumn = s.column(end)
+	function.Signature = collapseSpace(s.text(start, open))
+	s.completeDeclaration(function)
+}
+
+// completeDeclaration fills in the parameters, doc comment and visibility of a
+// function from its signature
+func (s *source) completeDeclaration(function *types.FunctionInfo) {
+	function.Params = splitParams(function.Signature, function.Name)
+	function.Doc = s.commentAbove(function.Line, "//")
+	function.Visibility = visibility(function.Language, function.Name, function.Signature)
+}
+
+// completePython completes a Python function, whose body is every following line
+// indented deeper than the def
+func (s *source) completePython(function *types.FunctionInfo) {
+	defLine := function.Line - 1
+	indent := indentWidth(s.lines[defLine])
+	function.Column = s.column(position{defLine, indent})
+
+	end := s.indentEnd(defLine)
+	function.EndLine = end + 1
+	function.EndColumn = utf8.RuneCountInString(strings.TrimRight(s.lines[end], " \t\r"))
+
+	signature := strings.TrimSpace(s.lines[defLine])
+	if colon := strings.LastIndex(signature, ":"); colon >= 0 {
+		signature = signature[:colon]
+	}
+	function.Signature = collapseSpace(signature)
+	function.Params = splitParams(function.Signature, function.Name)
+
+	function.Doc = s.docstring(defLine+1, end)
+	if function.Doc == "" {
+		function.Doc = s.commentAbove(function.Line, "#")
+	}
+	function.Visibility = visibility(function.Language, function.Name, function.Signature)
+}
+
+// classes returns the class-like declarations of the source
+func (s *source) classes(python bool) []class {
+	var classes []class
+	for i, line := range s.lines {
+		if python {
+			if match := pythonClassPattern.FindStringSubmatch(line); match != nil {
+				classes = append(classes, class{name: match[1], startLine: i + 1, endLine: s.indentEnd(i) + 1})
+			}
+			continue
+		}
+
+		match := classPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		open, found := s.find(position{i, 0}, '{')
+		if !found {
+			continue
+		}
+		if closing, ok := s.matchBrace(open); ok {
+			classes = append(classes, class{name: match[1], startLine: i + 1, endLine: closing.line + 1})
+		}
+	}
+	return classes
+}
+
+// innermostClass returns the name of the innermost class containing a line
+func innermostClass(classes []class, line int) string {
+	name := ""
+	start := 0
+	for _, c := range classes {
+		if c.startLine < line && line <= c.endLine && c.startLine > start {
+			name = c.name
+			start = c.startLine
+		}
+	}
+	return name
+}
+
+// qualifiedName joins a class and function name
+func qualifiedName(class, name string) string {
+	if class == "" {
+		return name
+	}
+	return class + "." + name
+}
+
+// find returns the first position of ch at or after start outside comments and strings
+func (s *source) find(start position, ch byte) (position, bool) {
+	offset := lexer.Index(s.segments, s.offset(start), ch)
+	if offset < 0 {
+		return position{}, false
+	}
+	return s.position(offset), true
+}
+
+// matchBrace returns the position of the brace closing the one at open, skipping
+// braces in comments and strings
+func (s *source) matchBrace(open position) (position, bool) {
+	offset := lexer.MatchBrace(s.segments, s.offset(open))
+	if offset < 0 {
+		return position{}, false
+	}
+	return s.position(offset), true
+}
+
+// indentEnd returns the last line of the block opened by the given line: every
+// following line indented deeper, along with lines continuing a bracket or string.
+// Blank and comment-only lines do not end the block. The line itself is returned
+// when nothing follows.
+func (s *source) indentEnd(line int) int {
+	indent := indentWidth(s.lines[line])
+	end := line
+	for i := line + 1; i < len(s.lines); i++ {
+		if s.continued != nil && s.continued[i] {
+			end = i
+			continue
+		}
+		text := strings.TrimSpace(s.lines[i])
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if indentWidth(s.lines[i]) <= indent {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+// offset converts a position to a byte offset in the content
+func (s *source) offset(p position) int {
+	return s.starts[p.line] + p.index
+}
+
+// position converts a byte offset in the content to a position
+func (s *source) position(offset int) position {
+	line := sort.Search(len(s.starts), func(i int) bool {
+		return s.starts[i] > offset
+	}) - 1
+	return position{line, offset - s.starts[line]}
+}
+
+// text returns the source from start up to, not including, end
+func (s *source) text(start, end position) string {
+	if start.line == end.line {
+		return s.lines[start.line][start.index:end.index]
+	}
+
+	parts := []string{s.lines[start.line][start.index:]}
+	parts = append(parts, s.lines[start.line+1:end.line]...)
+	parts = append(parts, s.lines[end.line][:end.index])
+	return strings.Join(parts, "\n")
+}
+
+// column converts a position to a 1-based column counted in characters
+func (s *source) column(p position) int {
+	return utf8.RuneCountInString(s.lines[p.line][:p.index]) + 1
+}
+
+// commentAbove returns the comment directly above a 1-based line, skipping
+// annotations and decorators, with the comment markers removed
+func (s *source) commentAbove(line int, lineMarker string) string {
+	i := line - 2
+	for i >= 0 && strings.HasPrefix(strings.TrimSpace(s.lines[i]), "@") {
+		i--
+	}
+	if i < 0 {
+		return ""
+	}
+
+	var comment []string
+	if strings.HasSuffix(strings.TrimSpace(s.lines[i]), "*/") {
+		for ; i >= 0; i-- {
+			text := strings.TrimSpace(s.lines[i])
+			opening := strings.Contains(text, "/*")
+			if opening {
+				text = text[strings.Index(text, "/*")+2:]
+			}
+			text = strings.TrimSpace(strings.TrimSuffix(text, "*/"))
+			text = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(text, "*"), "*"))
+			comment = append(comment, text)
+			if opening {
+				break
+			}
+		}
+	} else {
+		for ; i >= 0; i-- {
+			text := strings.TrimSpace(s.lines[i])
+			if !strings.HasPrefix(text, lineMarker) {
+				break
+			}
+			comment = append(comment, strings.TrimSpace(strings.TrimLeft(text, lineMarker[:1])))
+		}
+	}
+
+	// The lines were collected bottom up
+	for left, right := 0, len(comment)-1; left < right; left, right = left+1, right-1 {
+		comment[left], comment[right] = comment[right], comment[left]
+	}
+	return strings.TrimSpace(strings.Join(comment, "\n"))
+}
+
+// docstring returns the Python docstring starting on a 0-based line, if any
+func (s *source) docstring(line, end int) string {
+	if line > end || line >= len(s.lines) {
+		return ""
+	}
+
+	first := strings.TrimLeft(strings.TrimSpace(s.lines[line]), "rRuU")
+	var quote string
+	switch {
+	case strings.HasPrefix(first, `"""`):
+		quote = `"""`
+	case strings.HasPrefix(first, "'''"):
+		quote = "'''"
+	default:
+		return ""
+	}
+
+	var text []string
+	rest := strings.TrimPrefix(first, quote)
+	for i := line; i <= end; i++ {
+		if i > line {
+			rest = strings.TrimSpace(s.lines[i])
+		}
+		if closing := strings.Index(rest, quote); closing >= 0 {
+			text = append(text, rest[:closing])
+			return strings.TrimSpace(strings.Join(text, "\n"))
+		}
+		text = append(text, rest)
+	}
+	return ""
+}
+
+// splitParams returns the parameters listed in a signature after the function name
+func splitParams(signature, name string) []string {
+	start := strings.Index(signature, name+"(")
+	if start >= 0 {
+		start += len(name)
+	} else if start = strings.Index(signature, "("); start < 0 {
+		return nil
+	}
+
+	var params []string
+	depth := 0
+	from := start + 1
+	for i := start; i < len(signature); i++ {
+		switch signature[i] {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			if signature[i] == '>' && i > 0 && signature[i-1] == '=' {
+				// The arrow of a lambda default value
+				continue
+			}
+			depth--
+			if depth == 0 {
+				// A C parameter list of (void) declares no parameters
+				if param := strings.TrimSpace(signature[from:i]); param != "" && (param != "void" || params != nil) {
+					params = append(params, param)
+				}
+				return params
+			}
+		case ',':
+			if depth == 1 {
+				if param := strings.TrimSpace(signature[from:i]); param != "" {
+					params = append(params, param)
+				}
+				from = i + 1
+			}
+		}
+	}
+	return params
+}
+
+// visibility derives a function's visibility from its language's conventions
+// and the modifiers in its signature
+func visibility(language, name, signature string) string {
+	modifiers := signature
+	if index := strings.Index(signature, name+"("); index >= 0 {
+		modifiers = signature[:index]
+	}
+	hasModifier := func(modifier string) bool {
+		for _, word := range strings.Fields(modifiers) {
+			if word == modifier {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch language {
+	case "python":
+		if strings.HasPrefix(name, "_") && !(strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__")) {
+			return types.VisibilityPrivate
+		}
+	case "dart":
+		if strings.HasPrefix(name, "_") {
+			return types.VisibilityPrivate
+		}
+	case "java", "javascript", "typescript":
+		switch {
+		case hasModifier("private") || strings.HasPrefix(name, "#"):
+			return types.VisibilityPrivate
+		case hasModifier("protected"):
+			return types.VisibilityProtected
+		case language == "java" && !hasModifier("public"):
+			return types.VisibilityPackage
+		}
+	case "c", "cpp":
+		if hasModifier("static") {
+			return types.VisibilityPrivate
+		}
+	}
+	return types.VisibilityPublic
+}
+
+// indentWidth returns the byte length of a line's leading whitespace
+func indentWidth(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// collapseSpace joins the words of a signature with single spaces, dropping the
+// line breaks and trailing commas of parameter lists split over several lines
+func collapseSpace(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.NewReplacer("( ", "(", ", )", ")", ",)", ")", " )", ")").Replace(text)
+}
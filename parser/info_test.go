@@ -0,0 +1,78 @@
+package parser
+
+import "testing"
+
+func TestFunctionEnd(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		line     int
+		language string
+		want     int
+	}{
+		{
+			name:     "brace in a string",
+			content:  "func a() {\n\ts := \"}\"\n\treturn\n}\nfunc b() {}\n",
+			line:     1,
+			language: "go",
+			want:     4,
+		},
+		{
+			name:     "brace in comments",
+			content:  "void a() {\n    /* } */\n    x(); // }\n}\n",
+			line:     1,
+			language: "c",
+			want:     4,
+		},
+		{
+			name:     "brace in a regex",
+			content:  "function a(s) {\n  return /}/.test(s);\n}\n",
+			line:     1,
+			language: "javascript",
+			want:     3,
+		},
+		{
+			name:     "brace in a template",
+			content:  "function a(x: number) {\n  return `}${x}`;\n}\n",
+			line:     1,
+			language: "typescript",
+			want:     3,
+		},
+		{
+			name:     "allman brace",
+			content:  "int a(void)\n{\n    return 0;\n}\n",
+			line:     1,
+			language: "c",
+			want:     4,
+		},
+		{
+			name:     "unclosed brace runs to the end",
+			content:  "func a() {\n\tb()\n",
+			line:     1,
+			language: "go",
+			want:     3,
+		},
+		{
+			name:     "python block by indentation",
+			content:  "def a():\n    x = [\n1]\n\n    return x\n\ndef b():\n    pass\n",
+			line:     1,
+			language: "python",
+			want:     5,
+		},
+		{
+			name:     "python docstring with brackets",
+			content:  "def a():\n    \"\"\"Doc (\n}\"\"\"\n    pass\nb()\n",
+			line:     1,
+			language: "python",
+			want:     4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FunctionEnd(tt.content, tt.line, tt.language); got != tt.want {
+				t.Errorf("FunctionEnd() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
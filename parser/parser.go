@@ -2,6 +2,7 @@ package parser
 
 import (
 	"bufio"
+	"fmt"
 	"regexp"
 	"reviewer-bot/types"
 	"strings"
@@ -18,6 +19,9 @@ type GoParser struct{}
 // JavaScriptParser parses JavaScript functions
 type JavaScriptParser struct{}
 
+// TypeScriptParser parses TypeScript functions and class methods
+type TypeScriptParser struct{}
+
 // PythonParser parses Python functions
 type PythonParser struct{}
 
@@ -33,33 +37,6 @@ type DartParser struct{}
 // JavaParser parses Java functions
 type JavaParser struct{}
 
-// ParseFunctions parses Go functions
-func (p *GoParser) ParseFunctions(content string) []types.FunctionInfo {
-	var functions []types.FunctionInfo
-
-	// Regex for Go function definitions
-	// Matches: func FunctionName(params) returnType {
-	// Also handles methods: func (receiver) MethodName(params) returnType {
-	goFuncRegex := regexp.MustCompile(`^\s*func\s+(?:\([^)]+\)\s+)?([a-zA-Z_][a-zA-Z0-9_]*)\s*\([^)]*\)\s*(?:[^{]*)?\s*\{`)
-
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	lineNum := 1
-
-	for scanner.Scan() {
-		line := scanner.Text() // Don't trim, keep original indentation
-		if matches := goFuncRegex.FindStringSubmatch(line); matches != nil {
-			functions = append(functions, types.FunctionInfo{
-				Name:     matches[1],
-				Line:     lineNum,
-				Language: "go",
-			})
-		}
-		lineNum++
-	}
-
-	return functions
-}
-
 // ParseFunctions parses JavaScript functions
 func (p *JavaScriptParser) ParseFunctions(content string) []types.FunctionInfo {
 	var functions []types.FunctionInfo
@@ -100,7 +77,7 @@ func (p *JavaScriptParser) ParseFunctions(content string) []types.FunctionInfo {
 		lineNum++
 	}
 
-	return functions
+	return completeFunctions(content, functions)
 }
 
 // ParseFunctions parses Python functions
@@ -126,60 +103,7 @@ func (p *PythonParser) ParseFunctions(content string) []types.FunctionInfo {
 		lineNum++
 	}
 
-	return functions
-}
-
-// ParseFunctions parses C functions
-func (p *CParser) ParseFunctions(content string) []types.FunctionInfo {
-	var functions []types.FunctionInfo
-
-	// Regex for C function definitions
-	// Matches: return_type function_name(params) {
-	cFuncRegex := regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*\s+([a-zA-Z_][a-zA-Z0-9_]*)\s*\([^)]*\)\s*\{`)
-
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	lineNum := 1
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if matches := cFuncRegex.FindStringSubmatch(line); matches != nil {
-			functions = append(functions, types.FunctionInfo{
-				Name:     matches[1],
-				Line:     lineNum,
-				Language: "c",
-			})
-		}
-		lineNum++
-	}
-
-	return functions
-}
-
-// ParseFunctions parses C++ functions
-func (p *CppParser) ParseFunctions(content string) []types.FunctionInfo {
-	var functions []types.FunctionInfo
-
-	// Regex for C++ function definitions
-	// Matches: return_type function_name(params) {
-	// Also handles: return_type ClassName::function_name(params) {
-	cppFuncRegex := regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_<>]*\s+([a-zA-Z_][a-zA-Z0-9_]*)\s*\([^)]*\)\s*\{`)
-
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	lineNum := 1
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if matches := cppFuncRegex.FindStringSubmatch(line); matches != nil {
-			functions = append(functions, types.FunctionInfo{
-				Name:     matches[1],
-				Line:     lineNum,
-				Language: "cpp",
-			})
-		}
-		lineNum++
-	}
-
-	return functions
+	return completeFunctions(content, functions)
 }
 
 // ParseFunctions parses Dart functions
@@ -235,33 +159,7 @@ func (p *DartParser) ParseFunctions(content string) []types.FunctionInfo {
 		lineNum++
 	}
 
-	return functions
-}
-
-// ParseFunctions parses Java functions
-func (p *JavaParser) ParseFunctions(content string) []types.FunctionInfo {
-	var functions []types.FunctionInfo
-
-	// Regex for Java function definitions
-	// Matches: return_type function_name(params) {
-	javaFuncRegex := regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_<>]*\s+([a-zA-Z_][a-zA-Z0-9_]*)\s*\([^)]*\)\s*\{`)
-
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	lineNum := 1
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if matches := javaFuncRegex.FindStringSubmatch(line); matches != nil {
-			functions = append(functions, types.FunctionInfo{
-				Name:     matches[1],
-				Line:     lineNum,
-				Language: "java",
-			})
-		}
-		lineNum++
-	}
-
-	return functions
+	return completeFunctions(content, functions)
 }
 
 // GetParser returns the appropriate parser based on file extension
@@ -271,12 +169,20 @@ func GetParser(filePath string) Parser {
 	switch {
 	case strings.HasSuffix(lowerPath, ".go"):
 		return &GoParser{}
-	case strings.HasSuffix(lowerPath, ".js") || strings.HasSuffix(lowerPath, ".ts") || strings.HasSuffix(lowerPath, ".jsx") || strings.HasSuffix(lowerPath, ".tsx"):
+	case strings.HasSuffix(lowerPath, ".ts") || strings.HasSuffix(lowerPath, ".tsx"):
+		return &TypeScriptParser{}
+	case strings.HasSuffix(lowerPath, ".js") || strings.HasSuffix(lowerPath, ".jsx"):
 		return &JavaScriptParser{}
 	case strings.HasSuffix(lowerPath, ".py"):
 		return &PythonParser{}
 	case strings.HasSuffix(lowerPath, ".dart"):
 		return &DartParser{}
+	case strings.HasSuffix(lowerPath, ".c") || strings.HasSuffix(lowerPath, ".h"):
+		return &CParser{}
+	case strings.HasSuffix(lowerPath, ".cc") || strings.HasSuffix(lowerPath, ".cpp") || strings.HasSuffix(lowerPath, ".hpp"):
+		return &CppParser{}
+	case strings.HasSuffix(lowerPath, ".java"):
+		return &JavaParser{}
 	default:
 		// Default to JavaScript parser for unknown extensions
 		return &JavaScriptParser{}
@@ -286,5 +192,23 @@ func GetParser(filePath string) Parser {
 // ParseFile parses functions from a file based on its extension
 func ParseFile(filePath, content string) []types.FunctionInfo {
 	parser := GetParser(filePath)
-	return parser.ParseFunctions(content)
+	functions := parser.ParseFunctions(content)
+	seen := make(map[string]bool, len(functions))
+	for i := range functions {
+		id := FunctionID(functions[i])
+		if seen[id] {
+			// Overloads defined on the same line are told apart by their column
+			id = fmt.Sprintf("%s:%d", id, functions[i].Column)
+		}
+		seen[id] = true
+		functions[i].ID = id
+	}
+	return functions
+}
+
+// FunctionID returns a stable identifier for a function from its name and line;
+// ParseFile adds the column for functions sharing both, such as overloads
+// defined on one line
+func FunctionID(function types.FunctionInfo) string {
+	return fmt.Sprintf("%s@L%d", function.Name, function.Line)
 }
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"fmt"
+	"reviewer-bot/types"
+	"testing"
+)
+
+// wantFunction is the part of a parsed function the parser tests check
+type wantFunction struct {
+	id      string
+	endLine int
+	class   string
+}
+
+// checkFunctions compares parsed functions with the expected ones, in order
+func checkFunctions(t *testing.T, got []types.FunctionInfo, want []wantFunction) {
+	t.Helper()
+	if len(got) != len(want) {
+		ids := make([]string, len(got))
+		for i, function := range got {
+			ids[i] = function.ID
+		}
+		t.Fatalf("got %d functions %q, want %d", len(got), ids, len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.ID != w.id || g.EndLine != w.endLine || g.Class != w.class {
+			t.Errorf("function %d = {%s end %d class %q}, want {%s end %d class %q}",
+				i, g.ID, g.EndLine, g.Class, w.id, w.endLine, w.class)
+		}
+	}
+}
+
+func TestGetParser(t *testing.T) {
+	tests := []struct {
+		path string
+		want Parser
+	}{
+		{"main.go", &GoParser{}},
+		{"app.js", &JavaScriptParser{}},
+		{"app.jsx", &JavaScriptParser{}},
+		{"app.ts", &TypeScriptParser{}},
+		{"view.tsx", &TypeScriptParser{}},
+		{"script.py", &PythonParser{}},
+		{"main.c", &CParser{}},
+		{"list.h", &CParser{}},
+		{"main.cc", &CppParser{}},
+		{"main.cpp", &CppParser{}},
+		{"list.hpp", &CppParser{}},
+		{"Main.java", &JavaParser{}},
+		{"main.dart", &DartParser{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := GetParser(tt.path); fmt.Sprintf("%T", got) != fmt.Sprintf("%T", tt.want) {
+				t.Errorf("GetParser(%q) = %T, want %T", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFileOverloadIDs(t *testing.T) {
+	content := "class Shape {\npublic:\n    void over(int x) {} void over(double x) {}\n};\n"
+	checkFunctions(t, ParseFile("shape.cpp", content), []wantFunction{
+		{id: "over@L3", endLine: 3, class: "Shape"},
+		{id: "over@L3:25", endLine: 3, class: "Shape"},
+	})
+}
@@ -0,0 +1,348 @@
+package parser
+
+import (
+	"reviewer-bot/lexer"
+	"reviewer-bot/types"
+	"strings"
+)
+
+// declarationKind classifies the declaration in front of an opening brace
+type declarationKind int
+
+const (
+	// otherDeclaration is a block that is neither a function nor a scope, such as
+	// an initializer or a Java static block
+	otherDeclaration declarationKind = iota
+	functionDeclaration
+	classDeclaration
+	namespaceDeclaration
+)
+
+// declaration is what the text in front of an opening brace declares
+type declaration struct {
+	kind declarationKind
+	// name is the function or class name
+	name string
+	// qualifier is the class of a member defined out of line, as Foo in Foo::bar
+	qualifier string
+	// keyword is the class keyword of a class declaration, such as struct or enum
+	keyword string
+	// start is the index of the declaration in the text, past any annotations
+	start int
+	// initList is set for constructors followed by a member initializer list
+	initList bool
+}
+
+// scope is a class or namespace the scanner is inside of
+type scope struct {
+	kind    declarationKind
+	name    string
+	keyword string
+	// access is the current C++ access specifier of a class
+	access string
+	// enumConstants is set while the constants of a Java enum are being listed
+	enumConstants bool
+}
+
+// grammar is what the declaration scanner needs to know about a language
+type grammar struct {
+	language string
+	// preprocessor is set for languages with C preprocessor directives
+	preprocessor bool
+	// parse works out what a header, the code between the previous statement and
+	// an opening brace, declares; inClass is set directly inside a class body
+	parse func(header string, inClass bool) declaration
+	// extendsHeader reports whether the brace after a header belongs to the
+	// declaration rather than opening its body, as with a member initialized
+	// with braces or a type literal
+	extendsHeader func(header string, decl declaration) bool
+}
+
+// scanDeclarations finds the function definitions of brace-delimited source by
+// reading the declaration in front of every opening brace outside comments,
+// strings and preprocessor lines, so modifiers, annotations, generics and
+// signatures or braces on separate lines are all handled. Bodiless declarations
+// are skipped, as are the bodies of the functions found.
+func scanDeclarations(content string, g grammar) []types.FunctionInfo {
+	s := newSource(content, g.language)
+	masked := maskCode(content, s.segments, g.preprocessor)
+
+	var functions []types.FunctionInfo
+	var scopes []scope
+	headerStart := -1
+	for i := 0; i < len(masked); i++ {
+		c := masked[i]
+		switch {
+		case c == ';':
+			headerStart = -1
+			if len(scopes) > 0 {
+				scopes[len(scopes)-1].enumConstants = false
+			}
+		case c == '}':
+			headerStart = -1
+			if len(scopes) > 0 {
+				scopes = scopes[:len(scopes)-1]
+			}
+		case c == ':' && headerStart >= 0 && len(scopes) > 0 && scopes[len(scopes)-1].kind == classDeclaration:
+			// C++ access specifiers such as "public:" apply to the members after them
+			label := strings.TrimSpace(masked[headerStart:i])
+			if label == types.VisibilityPublic || label == types.VisibilityPrivate || label == types.VisibilityProtected {
+				scopes[len(scopes)-1].access = label
+				headerStart = -1
+			}
+		case c == '{':
+			header := ""
+			if headerStart >= 0 {
+				header = masked[headerStart:i]
+			}
+			inClass := len(scopes) > 0 && scopes[len(scopes)-1].kind == classDeclaration
+			decl := g.parse(header, inClass)
+			closing := matchMasked(masked, i)
+
+			if g.extendsHeader != nil && g.extendsHeader(header, decl) {
+				if closing < 0 {
+					return functions
+				}
+				i = closing
+				continue
+			}
+
+			switch {
+			case decl.kind == functionDeclaration && !inEnumConstants(scopes):
+				start := headerStart + decl.start
+				function := types.FunctionInfo{
+					Name:     decl.name,
+					Line:     s.position(start).line + 1,
+					Language: g.language,
+				}
+				s.completeBody(&function, s.position(start), s.position(i))
+				function.Class = decl.qualifier
+				if function.Class == "" {
+					if class := innermostScopeClass(scopes); class != nil {
+						function.Class = class.name
+						function.Visibility = memberVisibility(g.language, class, function.Visibility)
+					}
+				}
+				function.QualifiedName = qualifiedName(function.Class, function.Name)
+				functions = append(functions, function)
+			case decl.kind == classDeclaration || decl.kind == namespaceDeclaration:
+				access := types.VisibilityPrivate
+				if decl.keyword != "class" {
+					access = types.VisibilityPublic
+				}
+				scopes = append(scopes, scope{
+					kind:          decl.kind,
+					name:          decl.name,
+					keyword:       decl.keyword,
+					access:        access,
+					enumConstants: g.language == "java" && decl.keyword == "enum",
+				})
+				headerStart = -1
+				continue
+			}
+
+			// Function bodies and other blocks are skipped whole
+			if closing < 0 {
+				return functions
+			}
+			i = closing
+			headerStart = -1
+		case headerStart < 0 && !isSpaceByte(c):
+			headerStart = i
+		}
+	}
+	return functions
+}
+
+// className returns the class name declared by the text following a class
+// keyword, skipping macros and modifiers placed in front of it
+func className(text string) string {
+	name := ""
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ':' || c == '{' || c == '(' || c == '<':
+			return name
+		case isIdentByte(c):
+			start := i
+			for i < len(text) && isIdentByte(text[i]) {
+				i++
+			}
+			word := text[start:i]
+			switch word {
+			case "extends", "implements", "permits":
+				return name
+			case "class", "struct", "final", "sealed", "abstract":
+			default:
+				name = word
+			}
+		default:
+			i++
+		}
+	}
+	return name
+}
+
+// skipAnnotations returns the index of the first character of text past Java
+// annotations such as @Override or @SuppressWarnings("unchecked")
+func skipAnnotations(text string) int {
+	i := skipSpace(text, 0)
+	for i < len(text) && text[i] == '@' && !strings.HasPrefix(text[i:], "@interface") {
+		i++
+		for i < len(text) && (isIdentByte(text[i]) || text[i] == '.') {
+			i++
+		}
+		if next := skipSpace(text, i); next < len(text) && text[next] == '(' {
+			i = skipBalanced(text, next, '(', ')')
+		}
+		i = skipSpace(text, i)
+	}
+	return i
+}
+
+// skipBalanced returns the index just past the closing bracket matching the
+// opening one at i, or the end of text
+func skipBalanced(text string, i int, opening, closing byte) int {
+	depth := 0
+	for ; i < len(text); i++ {
+		switch text[i] {
+		case opening:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(text)
+}
+
+// skipAngles returns the index just past the template or generic arguments
+// opened at i, which may hold type literals in braces; a '<' that is not closed
+// is skipped on its own
+func skipAngles(text string, i int) int {
+	depth := 0
+	for j := i; j < len(text); j++ {
+		switch text[j] {
+		case '<':
+			depth++
+		case '>':
+			if j > 0 && text[j-1] == '=' {
+				// The arrow of a function type, as in Array<() => void>
+				continue
+			}
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		case '{':
+			if j = matchMasked(text, j); j < 0 {
+				return i + 1
+			}
+		case ';', '}':
+			return i + 1
+		}
+	}
+	return i + 1
+}
+
+// skipSpace returns the index of the first non-space character at or after i
+func skipSpace(text string, i int) int {
+	for i < len(text) && isSpaceByte(text[i]) {
+		i++
+	}
+	return i
+}
+
+// matchMasked returns the index of the brace closing the one at open in masked
+// code, or -1 when it is never closed
+func matchMasked(masked string, open int) int {
+	depth := 0
+	for i := open; i < len(masked); i++ {
+		switch masked[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// maskCode returns content with comments, string literals and, when
+// preprocessor is set, preprocessor lines blanked out, keeping every offset and
+// line break
+func maskCode(content string, segments []lexer.Segment, preprocessor bool) string {
+	masked := []byte(content)
+	for _, segment := range segments {
+		if segment.Kind == lexer.Code {
+			continue
+		}
+		blank(masked, segment.Offset, segment.Offset+len(segment.Text))
+	}
+
+	if preprocessor {
+		continued := false
+		offset := 0
+		for _, line := range strings.Split(string(masked), "\n") {
+			end := offset + len(line)
+			if continued || strings.HasPrefix(strings.TrimSpace(line), "#") {
+				// Directives continue onto the next line after a backslash
+				continued = strings.HasSuffix(strings.TrimRight(content[offset:end], " \t\r"), "\\")
+				blank(masked, offset, end)
+			}
+			offset = end + 1
+		}
+	}
+	return string(masked)
+}
+
+// blank replaces text[start:end] with spaces, keeping line breaks
+func blank(text []byte, start, end int) {
+	for i := start; i < end; i++ {
+		if text[i] != '\n' {
+			text[i] = ' '
+		}
+	}
+}
+
+// inEnumConstants reports whether the scanner is listing the constants of a Java enum
+func inEnumConstants(scopes []scope) bool {
+	return len(scopes) > 0 && scopes[len(scopes)-1].enumConstants
+}
+
+// innermostScopeClass returns the innermost enclosing class, or nil outside classes
+func innermostScopeClass(scopes []scope) *scope {
+	for i := len(scopes) - 1; i >= 0; i-- {
+		if scopes[i].kind == classDeclaration {
+			return &scopes[i]
+		}
+	}
+	return nil
+}
+
+// memberVisibility returns the visibility of a function defined inside a class:
+// the access specifier in effect in C++ and public for Java interface methods
+func memberVisibility(language string, class *scope, visibility string) string {
+	switch {
+	case language == "cpp":
+		return class.access
+	case language == "java" && class.keyword == "interface" && visibility == types.VisibilityPackage:
+		return types.VisibilityPublic
+	}
+	return visibility
+}
+
+// isIdentByte reports whether c can be part of an identifier
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// isSpaceByte reports whether c is a space, tab or line break
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
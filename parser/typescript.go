@@ -0,0 +1,244 @@
+package parser
+
+import (
+	"reviewer-bot/types"
+	"strings"
+)
+
+// tsModifiers may precede a TypeScript declaration or class member
+var tsModifiers = map[string]bool{
+	"export": true, "declare": true, "async": true, "public": true, "private": true, "protected": true,
+	"static": true, "readonly": true, "override": true, "abstract": true, "accessor": true,
+}
+
+// ParseFunctions parses TypeScript function declarations, functions and arrow
+// functions assigned to variables, and class methods, constructors, accessors and
+// arrow-function properties. Overload and abstract signatures, declarations and
+// interface members have no body and are skipped.
+func (p *TypeScriptParser) ParseFunctions(content string) []types.FunctionInfo {
+	return scanDeclarations(content, grammar{
+		language:      "typescript",
+		parse:         parseTSDeclaration,
+		extendsHeader: tsTypeBrace,
+	})
+}
+
+// parseTSDeclaration works out what a TypeScript header declares
+func parseTSDeclaration(header string, inClass bool) declaration {
+	decl := declaration{start: skipAnnotations(header)}
+	text := header[decl.start:]
+
+	// Skip modifiers, keeping them when they are the name of a member, as in async()
+	i := 0
+	exportDefault := false
+	for {
+		word, end := nextWord(text, i)
+		if !tsModifiers[word] && word != "default" {
+			break
+		}
+		if next := skipSpace(text, end); next < len(text) && strings.ContainsRune("(<=:?!;", rune(text[next])) {
+			break
+		}
+		exportDefault = exportDefault || word == "default"
+		i = end
+	}
+
+	word, end := nextWord(text, i)
+	next := skipSpace(text, end)
+	named := next < len(text) && (isIdentByte(text[next]) || text[next] == '"' || text[next] == '\'')
+	switch {
+	case word == "class" && (named || next == len(text) || text[next] == '<'):
+		decl.kind, decl.keyword = classDeclaration, word
+		decl.name = className(text[end:])
+		if decl.name == "" && exportDefault {
+			decl.name = "default"
+		}
+		return decl
+	case (word == "interface" || word == "enum" || word == "type") && named:
+		// Interface members are signatures and enum bodies hold no code
+		return declaration{}
+	case (word == "namespace" || word == "module") && named, word == "global" && next == len(text):
+		decl.kind = namespaceDeclaration
+		return decl
+	case word == "function":
+		if next < len(text) && text[next] == '*' {
+			end = next + 1
+		}
+		name, nameEnd := nextWord(text, end)
+		if name == "" {
+			if !exportDefault {
+				return declaration{}
+			}
+			name, nameEnd = "default", end
+		}
+		if tsParamsEnd(text, nameEnd) < 0 {
+			return declaration{}
+		}
+		decl.kind, decl.name = functionDeclaration, name
+		return decl
+	case word == "const" || word == "let" || word == "var":
+		name, nameEnd := nextWord(text, end)
+		if name != "" && isFunctionValue(text[nameEnd:]) {
+			decl.kind, decl.name = functionDeclaration, name
+			return decl
+		}
+		return declaration{}
+	case !inClass:
+		return declaration{}
+	}
+
+	// A class member: a method, accessor, constructor or arrow-function property
+	if star := skipSpace(text, i); star < len(text) && text[star] == '*' {
+		i = star + 1
+	}
+	name, nameEnd := memberName(text, i)
+	if name == "" {
+		return declaration{}
+	}
+	if nameEnd < len(text) && (text[nameEnd] == '?' || text[nameEnd] == '!') {
+		nameEnd++
+	}
+	if tsParamsEnd(text, nameEnd) >= 0 || isFunctionValue(text[nameEnd:]) {
+		decl.kind, decl.name = functionDeclaration, name
+	}
+	return decl
+}
+
+// memberName returns the name of the class member starting at i, looking past
+// get and set to the accessor's name, and the index just past it
+func memberName(text string, i int) (string, int) {
+	i = skipSpace(text, i)
+	private := i < len(text) && text[i] == '#'
+	if private {
+		i++
+	}
+	name, end := nextWord(text, i)
+	if (name == "get" || name == "set") && !private {
+		if accessor, accessorEnd := memberName(text, end); accessor != "" {
+			return accessor, accessorEnd
+		}
+	}
+	if private && name != "" {
+		name = "#" + name
+	}
+	return name, end
+}
+
+// tsParamsEnd returns the index past the parameter list that follows optional
+// type parameters at i, when only a return type annotation comes after it, or -1
+func tsParamsEnd(text string, i int) int {
+	i = skipSpace(text, i)
+	if i < len(text) && text[i] == '<' {
+		i = skipSpace(text, skipAngles(text, i))
+	}
+	if i >= len(text) || text[i] != '(' {
+		return -1
+	}
+	end := skipBalanced(text, i, '(', ')')
+	if rest := strings.TrimSpace(text[end:]); rest != "" && !strings.HasPrefix(rest, ":") {
+		return -1
+	}
+	return end
+}
+
+// isFunctionValue reports whether the text after a variable or property name
+// assigns it a function expression or an arrow function with a block body, as in
+// ": Handler = async (e: Event): Promise<void> =>"
+func isFunctionValue(text string) bool {
+	assign := -1
+	for i := 0; i < len(text) && assign < 0; {
+		switch c := text[i]; {
+		case c == '(':
+			i = skipBalanced(text, i, '(', ')')
+		case c == '[':
+			i = skipBalanced(text, i, '[', ']')
+		case c == '<' && i > 0 && isIdentByte(text[i-1]):
+			i = skipAngles(text, i)
+		case c == '=' && !strings.HasPrefix(text[i:], "=>") && !strings.HasPrefix(text[i:], "=="):
+			assign = i
+		default:
+			i++
+		}
+	}
+	if assign < 0 {
+		return false
+	}
+
+	value := strings.TrimSpace(text[assign+1:])
+	if word, end := nextWord(value, 0); word == "async" {
+		value = strings.TrimSpace(value[end:])
+	}
+	if word, _ := nextWord(value, 0); word == "function" {
+		return true
+	}
+
+	// An arrow function: (params) or a single parameter, then an optional return type
+	if word, end := nextWord(value, 0); word != "" {
+		return strings.TrimSpace(value[end:]) == "=>"
+	}
+	end := tsArrowParamsEnd(value)
+	if end < 0 {
+		return false
+	}
+	rest := strings.TrimSpace(value[end:])
+	return strings.HasSuffix(rest, "=>") && (rest == "=>" || strings.HasPrefix(rest, ":"))
+}
+
+// tsArrowParamsEnd returns the index past the type parameters and parameter list
+// an arrow function starts with, or -1
+func tsArrowParamsEnd(value string) int {
+	i := 0
+	if strings.HasPrefix(value, "<") {
+		i = skipSpace(value, skipAngles(value, 0))
+	}
+	if i >= len(value) || value[i] != '(' {
+		return -1
+	}
+	return skipBalanced(value, i, '(', ')')
+}
+
+// tsTypeBrace reports whether the brace after a header opens a type or a
+// destructuring pattern rather than a body: the header is inside brackets or
+// type arguments, or ends with a type annotation colon or a type operator
+func tsTypeBrace(header string, decl declaration) bool {
+	trimmed := strings.TrimSpace(header)
+	if trimmed == "" {
+		return false
+	}
+	switch trimmed[len(trimmed)-1] {
+	case ':', '|', '&', ',':
+		return true
+	}
+
+	depth := 0
+	for i := 0; i < len(header); i++ {
+		switch c := header[i]; {
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == '<' && i > 0 && isIdentByte(header[i-1]):
+			depth++
+		case c == '>' && i > 0 && header[i-1] != '=' && depth > 0:
+			depth--
+		case c == '{':
+			// Braces already taken into the header are balanced
+			i = matchMasked(header, i)
+			if i < 0 {
+				return false
+			}
+		}
+	}
+	return depth > 0
+}
+
+// nextWord returns the identifier at or after i, skipping spaces, and the index
+// just past it; the word is empty when something else comes first
+func nextWord(text string, i int) (string, int) {
+	i = skipSpace(text, i)
+	start := i
+	for i < len(text) && isIdentByte(text[i]) {
+		i++
+	}
+	return text[start:i], i
+}
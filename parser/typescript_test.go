@@ -0,0 +1,94 @@
+package parser
+
+import "testing"
+
+func TestTypeScriptParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []wantFunction
+	}{
+		{
+			name: "export default function",
+			content: `export default function handler(req: Request): Response {
+  return new Response("}");
+}
+`,
+			want: []wantFunction{{id: "handler@L1", endLine: 3}},
+		},
+		{
+			name: "typed arrow functions",
+			content: `export const add = (a: number, b: number): number => {
+  return a + b;
+};
+
+const pick = async <T,>(items: T[]): Promise<T> => {
+  const re = /[}]/g;
+  return items[0];
+};
+`,
+			want: []wantFunction{
+				{id: "add@L1", endLine: 3},
+				{id: "pick@L5", endLine: 8},
+			},
+		},
+		{
+			name: "class members",
+			content: "export class Store {\n" +
+				"  private cache = new Map<string, number>();\n" +
+				"\n" +
+				"  constructor(private readonly name: string) {}\n" +
+				"\n" +
+				"  public get(key: string): number | undefined {\n" +
+				"    return this.cache.get(`${key}}`);\n" +
+				"  }\n" +
+				"\n" +
+				"  private static reset(): void {\n" +
+				"    // }\n" +
+				"  }\n" +
+				"}\n",
+			want: []wantFunction{
+				{id: "constructor@L4", endLine: 4, class: "Store"},
+				{id: "get@L6", endLine: 8, class: "Store"},
+				{id: "reset@L10", endLine: 12, class: "Store"},
+			},
+		},
+		{
+			name: "overload signatures are skipped",
+			content: `function overload(x: string): string;
+function overload(x: number): number;
+function overload(x: any): any {
+  return x;
+}
+`,
+			want: []wantFunction{{id: "overload@L3", endLine: 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkFunctions(t, ParseFile("app.ts", tt.content), tt.want)
+		})
+	}
+}
+
+func TestTypeScriptMemberDetails(t *testing.T) {
+	content := "class Store {\n  private static reset(): void {}\n  protected async load(key: string): Promise<void> {}\n}\n"
+	want := []struct {
+		qualifiedName string
+		visibility    string
+	}{
+		{"Store.reset", "private"},
+		{"Store.load", "protected"},
+	}
+
+	functions := ParseFile("store.ts", content)
+	if len(functions) != len(want) {
+		t.Fatalf("got %d functions, want %d", len(functions), len(want))
+	}
+	for i, w := range want {
+		if got := functions[i]; got.QualifiedName != w.qualifiedName || got.Visibility != w.visibility {
+			t.Errorf("function %d = {%s %s}, want {%s %s}", i, got.QualifiedName, got.Visibility, w.qualifiedName, w.visibility)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies provider failures
+type ErrorKind string
+
+const (
+	// ErrQuota means the backend rejected the call for rate or quota limits
+	ErrQuota ErrorKind = "quota"
+	// ErrAuth means the credentials were missing or rejected
+	ErrAuth ErrorKind = "auth"
+	// ErrTransient means the call may succeed if retried
+	ErrTransient ErrorKind = "transient"
+	// ErrInvalidRequest means the request itself was rejected and retrying won't help
+	ErrInvalidRequest ErrorKind = "invalid_request"
+	// ErrUnavailable means the circuit breaker is open and the call was not attempted
+	ErrUnavailable ErrorKind = "unavailable"
+)
+
+// Error is a classified provider failure
+type Error struct {
+	Kind ErrorKind
+	// StatusCode is the HTTP status returned by the backend, if any
+	StatusCode int
+	// RetryAfter is the delay the backend asked for before retrying, if any
+	RetryAfter time.Duration
+	// Err carries the user-facing message
+	Err error
+}
+
+// Error returns the user-facing message
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the call may succeed if retried
+func (e *Error) Retryable() bool {
+	return e.Kind == ErrTransient || e.Kind == ErrQuota
+}
+
+// KindOf returns the kind of a provider error, or an empty kind for other errors
+func KindOf(err error) ErrorKind {
+	var providerErr *Error
+	if errors.As(err, &providerErr) {
+		return providerErr.Kind
+	}
+	return ""
+}
+
+// KindForStatus classifies an HTTP status code
+func KindForStatus(status int) ErrorKind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrQuota
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuth
+	case status == http.StatusRequestTimeout || status >= 500:
+		return ErrTransient
+	case status >= 400:
+		return ErrInvalidRequest
+	default:
+		return ErrTransient
+	}
+}
+
+// RequestError classifies a request to the named API that failed in transit:
+// a cancelled caller is reported as such, while a request that ran out of its
+// own timeout, derived from ctx as callCtx, can be retried
+func RequestError(ctx, callCtx context.Context, api string, timeout time.Duration, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s call cancelled: %w", api, ctx.Err())
+	}
+	if callCtx.Err() != nil {
+		err = fmt.Errorf("%s call timed out after %s", api, timeout)
+	}
+	return &Error{Kind: ErrTransient, Err: err}
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func ParseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reviewer-bot/types"
+	"strings"
+)
+
+// MockProvider generates canned reviews without calling any API
+type MockProvider struct{}
+
+// NewMockProvider creates a new mock review provider
+func NewMockProvider() *MockProvider {
+	return &MockProvider{}
+}
+
+// Capabilities reports which features the mock provider supports
+func (m *MockProvider) Capabilities() Capabilities {
+	return Capabilities{Batch: true}
+}
+
+// ModelName returns the name of the mock model
+func (m *MockProvider) ModelName(style string) string {
+	return "mock"
+}
+
+// GenerateReview generates a mock review for testing
+func (m *MockProvider) GenerateReview(ctx context.Context, functionName, functionCode, style string) (Response, error) {
+	mockReviews := map[string][]string{
+		"roast": {
+			"🔥 This function is more confusing than your ex's texts",
+			"😂 I've seen better code in a fortune cookie",
+			"🤦‍♂️ This function has more bugs than a picnic",
+			"😅 At least it compiles... barely",
+			"🤷‍♂️ It works, but at what cost?",
+		},
+		"funny": {
+			"😄 This function is so clean, it sparkles! ✨",
+			"🤣 Well, it's not the worst thing I've seen today",
+			"😊 Simple and effective - like a good dad joke",
+			"🎉 This function deserves a party!",
+			"😎 Cool function, bro!",
+		},
+		"motivational": {
+			"💪 You're doing great! This function rocks!",
+			"⭐ Keep up the excellent work!",
+			"🚀 This function is going places!",
+			"🌟 You've got this! Amazing job!",
+			"🔥 You're on fire! Keep coding!",
+		},
+		"technical": {
+			"🔧 Well-structured and efficient",
+			"📊 Good separation of concerns",
+			"⚡ Performance looks optimized",
+			"🛡️ Proper error handling implemented",
+			"📝 Clean and readable code",
+		},
+		"hilarious": {
+			"🤪 This function is so wild, it needs a leash! 🦮",
+			"🎭 Drama queen of functions right here! 👑",
+			"🤡 Clown code that somehow works! 🤹‍♂️",
+			"🎪 Welcome to the circus of functions! 🎪",
+			"🦄 Unicorn code - magical but questionable! ✨",
+		},
+	}
+
+	reviews, exists := mockReviews[strings.ToLower(style)]
+	if !exists {
+		reviews = mockReviews["funny"]
+	}
+
+	// Use function name to determine which mock review to use
+	index := len(functionName) % len(reviews)
+	review := reviews[index]
+
+	// Add random star rating (3-5 stars for mock reviews)
+	starCount := rand.Intn(3) + 3 // 3-5 stars
+	stars := ""
+	for i := 0; i < starCount; i++ {
+		stars += "⭐"
+	}
+
+	return Response{Text: stars + " " + review + "\n" + mockScoresLine()}, nil
+}
+
+// mockScoresLine returns a sub-scores line with random scores
+func mockScoresLine() string {
+	scores := mockScores()
+	return fmt.Sprintf("Scores: readability=%d, correctness_risk=%d, complexity=%d, error_handling=%d, naming=%d",
+		scores.Readability, scores.CorrectnessRisk, scores.Complexity, scores.ErrorHandling, scores.Naming)
+}
+
+// mockScores returns random sub-scores between 1 and 5
+func mockScores() *types.SubScores {
+	return &types.SubScores{
+		Readability:     rand.Intn(5) + 1,
+		CorrectnessRisk: rand.Intn(5) + 1,
+		Complexity:      rand.Intn(5) + 1,
+		ErrorHandling:   rand.Intn(5) + 1,
+		Naming:          rand.Intn(5) + 1,
+	}
+}
+
+// GenerateBatchReview generates mock batch reviews
+func (m *MockProvider) GenerateBatchReview(ctx context.Context, items []BatchItem, style string) (Response, error) {
+	mockReviews := map[string][]string{
+		"funny": {
+			"😄 This function is doing its best!",
+			"🤣 Well, it's not the worst thing I've seen today",
+			"😊 Simple and effective - like a good dad joke",
+			"🎯 Gets the job done, no questions asked!",
+			"🚀 This function is going places!",
+		},
+		"roast": {
+			"🔥 This function needs a reality check!",
+			"😂 At least it's not the worst code ever!",
+			"🤦‍♂️ I've seen better code in a tutorial!",
+			"💀 This function is barely alive!",
+			"🤡 Clown code that somehow works!",
+		},
+		"motivational": {
+			"💪 You're doing great! This function rocks!",
+			"⭐ Keep up the excellent work!",
+			"🚀 This function is going places!",
+			"🌟 You're making progress!",
+			"🎯 Every function counts!",
+		},
+		"technical": {
+			"🔧 Well-structured and efficient",
+			"📊 Good separation of concerns",
+			"⚡ Performance looks optimized",
+			"🎯 Clean and maintainable",
+			"📈 Scalable design pattern",
+		},
+		"hilarious": {
+			"🤪 This function is so wild, it needs a leash!",
+			"🎭 Drama queen of functions right here!",
+			"🤡 Clown code that somehow works!",
+			"🎪 Welcome to the circus!",
+			"🎨 Picasso would be proud!",
+		},
+	}
+
+	reviews, exists := mockReviews[strings.ToLower(style)]
+	if !exists {
+		reviews = mockReviews["funny"]
+	}
+
+	// Generate one mock review per function, echoing the IDs back
+	results := make([]BatchReviewItem, 0, len(items))
+	for i, item := range items {
+		results = append(results, BatchReviewItem{
+			ID:     item.ID,
+			Stars:  rand.Intn(3) + 3, // 3-5 stars
+			Review: reviews[i%len(reviews)],
+			Scores: mockScores(),
+		})
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Text: string(data)}, nil
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"reviewer-bot/types"
+)
+
+// Capabilities describes what a review provider supports
+type Capabilities struct {
+	Batch bool `json:"batch"`
+}
+
+// BatchItem is a single function sent in a batch review request
+type BatchItem struct {
+	// ID identifies the function in the structured batch response
+	ID   string
+	Name string
+	Code string
+}
+
+// BatchReviewItem is a single entry of a structured batch response
+type BatchReviewItem struct {
+	ID     string           `json:"id"`
+	Stars  int              `json:"stars"`
+	Review string           `json:"review"`
+	Scores *types.SubScores `json:"scores,omitempty"`
+}
+
+// Response is the reply of a single provider call
+type Response struct {
+	Text string
+	// Usage is the token usage reported by the backend; zero when it reports none
+	Usage types.Usage
+}
+
+// ReviewProvider is implemented by every model backend that can generate reviews
+type ReviewProvider interface {
+	// GenerateReview generates a review for a single function
+	GenerateReview(ctx context.Context, functionName, functionCode, style string) (Response, error)
+	// GenerateBatchReview generates reviews for multiple functions in a single call,
+	// replying with a JSON array of BatchReviewItem
+	GenerateBatchReview(ctx context.Context, items []BatchItem, style string) (Response, error)
+	// Capabilities reports which features the provider supports
+	Capabilities() Capabilities
+	// ModelName returns the name of the model used to generate reviews in the
+	// given style; an empty style names the default model
+	ModelName(style string) string
+}
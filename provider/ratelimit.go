@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a client-side limit on requests and prompt tokens per minute,
+// shared by every caller of the provider it guards
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+}
+
+// bucket is a token bucket refilled continuously up to its per-minute capacity
+type bucket struct {
+	capacity  float64
+	available float64
+	last      time.Time
+}
+
+// NewRateLimiter creates a rate limiter; a limit of 0 disables that dimension
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	return &RateLimiter{
+		requests: newBucket(requestsPerMinute),
+		tokens:   newBucket(tokensPerMinute),
+	}
+}
+
+// newBucket creates a full bucket, or nil when the limit is disabled
+func newBucket(perMinute int) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &bucket{capacity: float64(perMinute), available: float64(perMinute), last: time.Now()}
+}
+
+// reserve takes n units from the bucket and returns how long the caller must wait
+// for them; the balance may go negative so later callers queue behind earlier ones
+func (b *bucket) reserve(n float64, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	perSecond := b.capacity / 60
+	b.available += now.Sub(b.last).Seconds() * perSecond
+	if b.available > b.capacity {
+		b.available = b.capacity
+	}
+	b.last = now
+
+	// A single request larger than the whole budget waits for a full bucket only
+	if n > b.capacity {
+		n = b.capacity
+	}
+	b.available -= n
+	if b.available >= 0 {
+		return 0
+	}
+	return time.Duration(-b.available / perSecond * float64(time.Second))
+}
+
+// Wait blocks until a request of the given token size fits within the limits
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	wait := l.requests.reserve(1, now)
+	if tokenWait := l.tokens.reserve(float64(tokens), now); tokenWait > wait {
+		wait = tokenWait
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
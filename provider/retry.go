@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// errCallTimeout is the cause of a context ended by the per-call timeout
+var errCallTimeout = errors.New("model call timed out")
+
+// Cancelled reports whether ctx was cancelled or ran past its deadline for a
+// reason other than the per-call timeout of a RetryingProvider, which is
+// retried like any transient failure
+func Cancelled(ctx context.Context) bool {
+	return ctx.Err() != nil && !errors.Is(context.Cause(ctx), errCallTimeout)
+}
+
+// RetryPolicy controls how failed calls are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on every retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff and the longest server retry hint that is honoured
+	MaxDelay time.Duration
+	// CallTimeout bounds each attempt, not counting the rate limiter wait; 0 leaves
+	// it to the backend's own request timeout
+	CallTimeout time.Duration
+}
+
+// CircuitBreaker stops calling a backend after repeated consecutive failures
+type CircuitBreaker struct {
+	// Threshold is the number of consecutive failures that opens the breaker
+	Threshold int
+	// Cooldown is how long the breaker stays open before a trial call is allowed
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	// probing is set while the single trial call of a half-open breaker runs
+	probing bool
+}
+
+// NewCircuitBreaker creates a circuit breaker; a threshold of 0 disables it
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// Allow reports whether a call may be attempted. Once the cooldown has passed a
+// single trial call is let through, and every caller allowed must report its
+// outcome with Record.
+func (b *CircuitBreaker) Allow() bool {
+	if b == nil || b.Threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.Threshold {
+		return true
+	}
+	// Half-open: let one trial call through once the cooldown has passed and turn
+	// others away until it reports back
+	if b.probing || time.Since(b.openedAt) < b.Cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Record updates the breaker with the outcome of a call
+func (b *CircuitBreaker) Record(err error) {
+	if b == nil || b.Threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	kind := KindOf(err)
+	switch {
+	case err == nil:
+		b.failures = 0
+	case kind == "" || kind == ErrInvalidRequest || kind == ErrUnavailable:
+		// Not a backend failure: cancellations, bad requests, calls that never happened
+	default:
+		b.failures++
+		if b.failures >= b.Threshold {
+			b.openedAt = time.Now()
+		}
+	}
+}
+
+// RetryingProvider wraps a provider with retries, a circuit breaker and a
+// shared rate limiter
+type RetryingProvider struct {
+	ReviewProvider
+	policy  RetryPolicy
+	breaker *CircuitBreaker
+	limiter *RateLimiter
+}
+
+// NewRetryingProvider wraps p so transient and quota errors are retried with
+// jittered exponential backoff, repeated failures open the circuit breaker and
+// every attempt waits for the limiter
+func NewRetryingProvider(p ReviewProvider, policy RetryPolicy, breaker *CircuitBreaker, limiter *RateLimiter) *RetryingProvider {
+	return &RetryingProvider{
+		ReviewProvider: p,
+		policy:         policy,
+		breaker:        breaker,
+		limiter:        limiter,
+	}
+}
+
+// GenerateReview generates a review for a single function, retrying on failure
+func (r *RetryingProvider) GenerateReview(ctx context.Context, functionName, functionCode, style string) (Response, error) {
+	return r.call(ctx, EstimateReviewTokens(functionName, functionCode), func(ctx context.Context) (Response, error) {
+		return r.ReviewProvider.GenerateReview(ctx, functionName, functionCode, style)
+	})
+}
+
+// GenerateBatchReview generates reviews for multiple functions, retrying on failure
+func (r *RetryingProvider) GenerateBatchReview(ctx context.Context, items []BatchItem, style string) (Response, error) {
+	return r.call(ctx, EstimateBatchTokens(items), func(ctx context.Context) (Response, error) {
+		return r.ReviewProvider.GenerateBatchReview(ctx, items, style)
+	})
+}
+
+// call runs generate until it succeeds, fails permanently or runs out of retries;
+// every attempt waits for the limiter to admit a prompt of the given size
+func (r *RetryingProvider) call(ctx context.Context, tokens int, generate func(context.Context) (Response, error)) (Response, error) {
+	for attempt := 0; ; attempt++ {
+		if !r.breaker.Allow() {
+			return Response{}, &Error{
+				Kind: ErrUnavailable,
+				Err:  fmt.Errorf("%s backend is failing repeatedly, skipping calls for %v", r.ModelName(""), r.breaker.Cooldown),
+			}
+		}
+		if err := r.limiter.Wait(ctx, tokens); err != nil {
+			r.breaker.Record(err)
+			return Response{}, err
+		}
+
+		result, err := r.attempt(ctx, generate)
+		if ctx.Err() != nil {
+			// Cancellation says nothing about the backend's health
+			r.breaker.Record(ctx.Err())
+			return result, err
+		}
+		r.breaker.Record(err)
+		if err == nil {
+			return result, nil
+		}
+
+		var providerErr *Error
+		if !errors.As(err, &providerErr) || !providerErr.Retryable() || attempt >= r.policy.MaxRetries {
+			return Response{}, err
+		}
+
+		delay := r.backoff(attempt)
+		if providerErr.RetryAfter > delay {
+			delay = providerErr.RetryAfter
+		}
+		if r.policy.MaxDelay > 0 && delay > r.policy.MaxDelay {
+			// The server wants us to wait longer than we are willing to
+			return Response{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return Response{}, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// attempt runs generate once, within the per-call timeout when one is set
+func (r *RetryingProvider) attempt(ctx context.Context, generate func(context.Context) (Response, error)) (Response, error) {
+	if r.policy.CallTimeout <= 0 {
+		return generate(ctx)
+	}
+
+	callCtx, cancel := context.WithTimeoutCause(ctx, r.policy.CallTimeout, errCallTimeout)
+	defer cancel()
+	result, err := generate(callCtx)
+	if err != nil && ctx.Err() == nil && callCtx.Err() != nil && KindOf(err) == "" {
+		// Only this attempt ran out of time, which is worth another try
+		err = &Error{Kind: ErrTransient, Err: fmt.Errorf("model call timed out after %v: %v", r.policy.CallTimeout, err)}
+	}
+	return result, err
+}
+
+// backoff returns a jittered exponential delay for the given retry attempt
+func (r *RetryingProvider) backoff(attempt int) time.Duration {
+	if r.policy.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := r.policy.BaseDelay << attempt
+	if r.policy.MaxDelay > 0 && (delay > r.policy.MaxDelay || delay <= 0) {
+		delay = r.policy.MaxDelay
+	}
+	// Equal jitter: half fixed, half random
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// scriptedProvider replies to single reviews with a fixed sequence of errors,
+// then succeeds
+type scriptedProvider struct {
+	MockProvider
+	errs  []error
+	calls int
+}
+
+func (p *scriptedProvider) GenerateReview(ctx context.Context, functionName, functionCode, style string) (Response, error) {
+	p.calls++
+	if p.calls <= len(p.errs) {
+		return Response{}, p.errs[p.calls-1]
+	}
+	return Response{Text: "⭐⭐⭐⭐ Fine."}, nil
+}
+
+func TestBackoff(t *testing.T) {
+	r := NewRetryingProvider(nil, RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, nil, nil)
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		// Shifting this far overflows and must still be capped
+		{70, time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := r.backoff(tt.attempt); got < tt.max/2 || got > tt.max {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+
+	if got := NewRetryingProvider(nil, RetryPolicy{}, nil, nil).backoff(3); got != 0 {
+		t.Errorf("backoff() without a base delay = %v, want 0", got)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	transient := &Error{Kind: ErrTransient, Err: errors.New("server error")}
+	b := NewCircuitBreaker(2, time.Minute)
+
+	b.Record(transient)
+	b.Record(&Error{Kind: ErrInvalidRequest, Err: errors.New("bad request")})
+	b.Record(context.Canceled)
+	if !b.Allow() {
+		t.Fatal("breaker opened on failures that are not the backend's")
+	}
+	b.Record(transient)
+	if b.Allow() {
+		t.Fatal("breaker allowed a call after reaching the threshold")
+	}
+
+	// Half-open: a single trial call once the cooldown has passed
+	b.openedAt = time.Now().Add(-2 * time.Minute)
+	if !b.Allow() {
+		t.Fatal("breaker refused the trial call after the cooldown")
+	}
+	if b.Allow() {
+		t.Fatal("breaker allowed a second call while the trial call runs")
+	}
+	b.Record(transient)
+	if b.Allow() {
+		t.Fatal("breaker allowed a call after the trial call failed")
+	}
+
+	b.openedAt = time.Now().Add(-2 * time.Minute)
+	if !b.Allow() {
+		t.Fatal("breaker refused the trial call after the second cooldown")
+	}
+	b.Record(nil)
+	if !b.Allow() || !b.Allow() {
+		t.Error("breaker stayed open after the trial call succeeded")
+	}
+
+	var disabled *CircuitBreaker
+	if !disabled.Allow() || !NewCircuitBreaker(0, time.Minute).Allow() {
+		t.Error("a nil or zero-threshold breaker refused a call")
+	}
+}
+
+func TestRetryingProvider(t *testing.T) {
+	transient := &Error{Kind: ErrTransient, Err: errors.New("server error")}
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantKind  ErrorKind
+	}{
+		{"success", nil, 1, ""},
+		{"retries transient errors", []error{transient, transient}, 3, ""},
+		{"gives up after the retries", []error{transient, transient, transient}, 3, ErrTransient},
+		{"stops on invalid requests", []error{&Error{Kind: ErrInvalidRequest, Err: errors.New("bad request")}}, 1, ErrInvalidRequest},
+		{"stops on auth errors", []error{&Error{Kind: ErrAuth, Err: errors.New("bad key")}}, 1, ErrAuth},
+		{"stops when the server asks for too long a wait", []error{&Error{Kind: ErrQuota, RetryAfter: time.Hour, Err: errors.New("slow down")}}, 1, ErrQuota},
+	}
+
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &scriptedProvider{errs: tt.errs}
+			_, err := NewRetryingProvider(backend, policy, nil, nil).GenerateReview(context.Background(), "f", "func f() {}", "")
+			if backend.calls != tt.wantCalls {
+				t.Errorf("backend called %d times, want %d", backend.calls, tt.wantCalls)
+			}
+			if KindOf(err) != tt.wantKind || (err != nil) != (tt.wantKind != "") {
+				t.Errorf("GenerateReview() error = %v, want kind %q", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestRetryingProviderOpenBreaker(t *testing.T) {
+	transient := &Error{Kind: ErrTransient, Err: errors.New("server error")}
+	backend := &scriptedProvider{errs: []error{transient, transient, transient}}
+	r := NewRetryingProvider(backend, RetryPolicy{}, NewCircuitBreaker(2, time.Minute), nil)
+
+	for i := 0; i < 3; i++ {
+		r.GenerateReview(context.Background(), "f", "func f() {}", "")
+	}
+	if backend.calls != 2 {
+		t.Errorf("backend called %d times, want 2 before the breaker opened", backend.calls)
+	}
+	if _, err := r.GenerateReview(context.Background(), "f", "func f() {}", ""); KindOf(err) != ErrUnavailable {
+		t.Errorf("GenerateReview() with an open breaker error = %v, want kind %q", err, ErrUnavailable)
+	}
+}
+
+// slowProvider replies once its context is done
+type slowProvider struct {
+	MockProvider
+}
+
+func (p *slowProvider) GenerateReview(ctx context.Context, functionName, functionCode, style string) (Response, error) {
+	<-ctx.Done()
+	return Response{}, ctx.Err()
+}
+
+func TestRetryingProviderCallTimeout(t *testing.T) {
+	r := NewRetryingProvider(&slowProvider{}, RetryPolicy{CallTimeout: time.Millisecond}, nil, nil)
+	_, err := r.GenerateReview(context.Background(), "f", "func f() {}", "")
+	if KindOf(err) != ErrTransient {
+		t.Errorf("GenerateReview() past the call timeout error = %v, want kind %q", err, ErrTransient)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.GenerateReview(ctx, "f", "func f() {}", ""); KindOf(err) != "" || !Cancelled(ctx) {
+		t.Errorf("GenerateReview() with a cancelled context error = %v, want the cancellation", err)
+	}
+}
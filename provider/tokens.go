@@ -0,0 +1,33 @@
+package provider
+
+import "unicode/utf8"
+
+// promptOverheadTokens approximates the instructions added around each function
+const promptOverheadTokens = 300
+
+// EstimateTokens roughly estimates the token count of a text, assuming about
+// four characters per token as most tokenizers do for source code
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// EstimateReviewTokens estimates the prompt tokens of a single review request
+func EstimateReviewTokens(functionName, functionCode string) int {
+	return promptOverheadTokens + EstimateTokens(functionName) + EstimateTokens(functionCode)
+}
+
+// EstimateBatchTokens estimates the prompt tokens of a batch review request
+func EstimateBatchTokens(items []BatchItem) int {
+	total := promptOverheadTokens
+	for _, item := range items {
+		total += EstimateBatchItemTokens(item)
+	}
+	return total
+}
+
+// EstimateBatchItemTokens estimates the prompt tokens one function adds to a batch
+func EstimateBatchItemTokens(item BatchItem) int {
+	// Labels and code markers around each function
+	const itemOverheadTokens = 25
+	return itemOverheadTokens + EstimateTokens(item.ID) + EstimateTokens(item.Name) + EstimateTokens(item.Code)
+}
@@ -0,0 +1,175 @@
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// detector finds one kind of sensitive value
+type detector struct {
+	kind    string
+	pattern *regexp.Regexp
+	// group is the submatch that is replaced; 0 replaces the whole match
+	group int
+	// check filters candidate matches, e.g. by entropy
+	check func(value string) bool
+}
+
+// builtinDetectors run in order, so that whole private keys are replaced before
+// anything inside them could match a narrower detector
+var builtinDetectors = []detector{
+	{kind: "private_key", pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`)},
+	{kind: "connection_string", pattern: regexp.MustCompile("\\b[a-zA-Z][a-zA-Z0-9+.\\-]*://([^\\s:/@\"'`]+:[^\\s@\"'`]+)@"), group: 1},
+	{kind: "aws_key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{kind: "github_token", pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{kind: "slack_token", pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{kind: "google_api_key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{kind: "stripe_key", pattern: regexp.MustCompile(`\b(?:sk|rk|pk)_(?:live|test)_[0-9A-Za-z]{16,}\b`)},
+	{kind: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{8,}\.eyJ[A-Za-z0-9_\-]{8,}\.[A-Za-z0-9_\-]{8,}\b`)},
+	{kind: "generic_secret", pattern: regexp.MustCompile("(?i)(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?key|auth[_-]?token|token)[\"']?\\s*[:=]+\\s*[\"'`]([^\"'`\\s]{6,})[\"'`]"), group: 1},
+	{kind: "email", pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`)},
+}
+
+// entropyDetector catches random-looking string literals that no key format matched
+var entropyDetector = detector{
+	kind:    "high_entropy",
+	pattern: regexp.MustCompile("[\"'`]([A-Za-z0-9+/=_\\-.]{20,})[\"'`]"),
+	group:   1,
+	check:   isHighEntropy,
+}
+
+// Redactor replaces secrets and personal data in code with placeholders
+type Redactor struct {
+	detectors []detector
+}
+
+// Result is redacted code and the number of redactions made per kind
+type Result struct {
+	Code   string
+	Counts map[string]int
+}
+
+// Total returns the number of redactions made
+func (r Result) Total() int {
+	total := 0
+	for _, count := range r.Counts {
+		total += count
+	}
+	return total
+}
+
+// New creates a redactor with the built-in detectors and the given custom
+// regular expressions
+func New(customPatterns []string) (*Redactor, error) {
+	detectors := append([]detector{}, builtinDetectors...)
+	for _, custom := range customPatterns {
+		pattern, err := regexp.Compile(custom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %v", custom, err)
+		}
+		detectors = append(detectors, detector{kind: "custom", pattern: pattern})
+	}
+	detectors = append(detectors, entropyDetector)
+	return &Redactor{detectors: detectors}, nil
+}
+
+// Redact replaces every detected value in code with a placeholder such as
+// [REDACTED_EMAIL_1]; repeated values get the same placeholder
+func (r *Redactor) Redact(code string) Result {
+	result := Result{Code: code, Counts: map[string]int{}}
+	if r == nil {
+		return result
+	}
+
+	placeholders := map[string]string{}
+	distinct := map[string]int{}
+	for _, d := range r.detectors {
+		result.Code = d.replace(result.Code, func(value string) string {
+			result.Counts[d.kind]++
+			if placeholder, ok := placeholders[value]; ok {
+				return placeholder
+			}
+			distinct[d.kind]++
+			placeholder := fmt.Sprintf("[REDACTED_%s_%d]", strings.ToUpper(d.kind), distinct[d.kind])
+			placeholders[value] = placeholder
+			return placeholder
+		})
+	}
+
+	if len(result.Counts) == 0 {
+		result.Counts = nil
+	}
+	return result
+}
+
+// replace substitutes every accepted match of the detector's group
+func (d detector) replace(code string, placeholder func(value string) string) string {
+	matches := d.pattern.FindAllStringSubmatchIndex(code, -1)
+	if len(matches) == 0 {
+		return code
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[2*d.group], match[2*d.group+1]
+		if start < 0 || start < last {
+			continue
+		}
+		value := code[start:end]
+		if d.check != nil && !d.check(value) {
+			continue
+		}
+		out.WriteString(code[last:start])
+		out.WriteString(placeholder(value))
+		last = end
+	}
+	out.WriteString(code[last:])
+	return out.String()
+}
+
+// isHighEntropy reports whether a string looks like a random token rather than
+// words, paths or identifiers
+func isHighEntropy(value string) bool {
+	hasDigit := strings.IndexFunc(value, unicode.IsDigit) >= 0
+	hasLetter := strings.IndexFunc(value, unicode.IsLetter) >= 0
+	if !hasDigit || !hasLetter {
+		return false
+	}
+	return shannonEntropy(value) >= 3.5
+}
+
+// shannonEntropy returns the entropy of a string in bits per character
+func shannonEntropy(value string) float64 {
+	counts := map[rune]int{}
+	for _, ch := range value {
+		counts[ch]++
+	}
+
+	length := float64(len([]rune(value)))
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Describe formats redaction counts as "email=2, aws_key=1" in a stable order
+func Describe(counts map[string]int) string {
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s=%d", kind, counts[kind]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,47 @@
+package review
+
+import (
+	"reviewer-bot/types"
+	"strings"
+)
+
+// sentFunction returns the function as the provider sees it, with its name and
+// ID anonymized when anonymization is enabled
+func (g *Generator) sentFunction(function types.FunctionInfo) types.FunctionInfo {
+	if g.anonymizer == nil {
+		return function
+	}
+
+	// Keep the line and any column distinguishing overloads
+	suffix := strings.TrimPrefix(function.ID, function.Name)
+	function.Name = g.anonymizer.Name(function.Name)
+	function.ID = function.Name + suffix
+	return function
+}
+
+// restoreBatch maps the anonymized IDs, names and tokens in parsed batch results
+// back to the original functions
+func (g *Generator) restoreBatch(reviews []types.Review, batchErrors []types.BatchError, functions []types.FunctionInfo) {
+	if g.anonymizer == nil {
+		return
+	}
+
+	originals := make(map[string]types.FunctionInfo, len(functions))
+	for _, function := range functions {
+		originals[g.sentFunction(function).ID] = function
+	}
+
+	for i := range reviews {
+		original := originals[reviews[i].ID]
+		reviews[i].ID = original.ID
+		reviews[i].Function = original.Name
+		reviews[i].Review = g.anonymizer.Restore(reviews[i].Review)
+	}
+	for i := range batchErrors {
+		if original, ok := originals[batchErrors[i].ID]; ok {
+			batchErrors[i].ID = original.ID
+			batchErrors[i].Function = original.Name
+		}
+		batchErrors[i].Error = g.anonymizer.Restore(batchErrors[i].Error)
+	}
+}
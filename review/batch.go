@@ -0,0 +1,94 @@
+package review
+
+import (
+	"fmt"
+	"reviewer-bot/provider"
+	"reviewer-bot/types"
+	"strings"
+)
+
+// functionCode extracts a function's code with secrets redacted and identifiers
+// anonymized when enabled, truncating it when it exceeds the configured token limit
+func (g *Generator) functionCode(fileContent string, function types.FunctionInfo) string {
+	code := g.options.Redactor.Redact(FunctionCode(fileContent, function)).Code
+	code = g.anonymizer.Code(code, function.Language)
+	return truncateCode(code, g.options.MaxFunctionTokens)
+}
+
+// batchItem builds the batch entry sent for a function
+func (g *Generator) batchItem(fileContent string, function types.FunctionInfo) provider.BatchItem {
+	sent := g.sentFunction(function)
+	return provider.BatchItem{
+		ID:   sent.ID,
+		Name: sent.Name,
+		Code: g.functionCode(fileContent, function),
+	}
+}
+
+// redactions returns the number of redactions made in a function's code per kind
+func (g *Generator) redactions(fileContent string, function types.FunctionInfo) map[string]int {
+	return g.options.Redactor.Redact(FunctionCode(fileContent, function)).Counts
+}
+
+// splitBatches packs functions, in order, into batches whose estimated prompt
+// size stays under the batch token budget
+func (g *Generator) splitBatches(functions []types.FunctionInfo, fileContent string) [][]types.FunctionInfo {
+	var batches [][]types.FunctionInfo
+	var current []types.FunctionInfo
+	currentTokens := provider.EstimateBatchTokens(nil)
+
+	for _, function := range functions {
+		itemTokens := provider.EstimateBatchItemTokens(g.batchItem(fileContent, function))
+
+		if len(current) > 0 && currentTokens+itemTokens > g.options.BatchTokenBudget {
+			batches = append(batches, current)
+			current = nil
+			currentTokens = provider.EstimateBatchTokens(nil)
+		}
+		current = append(current, function)
+		currentTokens += itemTokens
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+// truncateCode keeps the head and tail of code whose estimated size exceeds
+// maxTokens, replacing the middle with a marker line
+func truncateCode(code string, maxTokens int) string {
+	if maxTokens <= 0 || provider.EstimateTokens(code) <= maxTokens {
+		return code
+	}
+
+	lines := strings.Split(code, "\n")
+	headBudget := maxTokens * 2 / 3
+	tailBudget := maxTokens - headBudget
+
+	head := 0
+	for used := 0; head < len(lines); head++ {
+		used += provider.EstimateTokens(lines[head]) + 1
+		if used > headBudget {
+			break
+		}
+	}
+
+	tail := len(lines)
+	for used := 0; tail > head; tail-- {
+		used += provider.EstimateTokens(lines[tail-1]) + 1
+		if used > tailBudget {
+			break
+		}
+	}
+
+	if head == 0 && tail == len(lines) {
+		// A single huge line: cut it by characters
+		runes := []rune(code)
+		return string(runes[:maxTokens*4]) + "\n... [truncated] ..."
+	}
+
+	marker := fmt.Sprintf("... [%d lines truncated] ...", tail-head)
+	parts := append(append(append([]string{}, lines[:head]...), marker), lines[tail:]...)
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,133 @@
+package review
+
+import (
+	"errors"
+	"regexp"
+	"reviewer-bot/provider"
+	"reviewer-bot/types"
+	"sort"
+	"strings"
+)
+
+// expectedResponseTokens approximates the reply for one function: a rating, a
+// one-liner and the sub-scores
+const expectedResponseTokens = 80
+
+// errBudgetExhausted is returned for calls skipped because the budget ran out
+var errBudgetExhausted = errors.New("budget exhausted")
+
+// branchPattern matches the constructs counted towards a function's complexity
+var branchPattern = regexp.MustCompile(`\b(if|elif|else if|for|while|case|catch|except)\b|&&|\|\||\?`)
+
+// estimateUsage returns the expected usage of a call sending promptTokens for the
+// given number of functions, priced for the model of the style
+func (g *Generator) estimateUsage(promptTokens, functions int, style string) types.Usage {
+	estimate := types.Usage{
+		PromptTokens:   promptTokens,
+		ResponseTokens: functions * expectedResponseTokens,
+		Estimated:      true,
+	}
+	estimate.TotalTokens = estimate.PromptTokens + estimate.ResponseTokens
+	estimate.Cost = g.options.Prices.Cost(g.provider.ModelName(style), estimate)
+	return estimate
+}
+
+// selectWithinBudget orders the functions by priority and returns those whose
+// estimated usage fits the remaining budget; the others are stored in reviewsByID
+// as budget exhausted
+func (g *Generator) selectWithinBudget(functions []types.FunctionInfo, fileContent, style string, reviewsByID map[string]types.Review) []types.FunctionInfo {
+	if g.options.Budget == nil || len(functions) == 0 {
+		return functions
+	}
+
+	// Batched functions share the prompt overhead
+	batched := len(functions) > 1 && g.provider.Capabilities().Batch
+	var planned types.Usage
+	if batched {
+		planned = g.estimateUsage(provider.EstimateBatchTokens(nil), 0, style)
+	}
+
+	var selected []types.FunctionInfo
+	for _, function := range g.prioritize(functions, fileContent) {
+		code := g.functionCode(fileContent, function)
+		estimate := g.estimateUsage(provider.EstimateReviewTokens(function.Name, code), 1, style)
+		if batched {
+			item := provider.BatchItem{ID: function.ID, Name: function.Name, Code: code}
+			estimate = g.estimateUsage(provider.EstimateBatchItemTokens(item), 1, style)
+		}
+
+		next := planned
+		next.Add(estimate)
+		if !g.options.Budget.Fits(next) {
+			reviewsByID[function.ID] = budgetExhaustedReview(function, style)
+			continue
+		}
+		planned = next
+		selected = append(selected, function)
+	}
+	return selected
+}
+
+// prioritize orders functions so that changed functions come first, then the most
+// complex ones, then by line
+func (g *Generator) prioritize(functions []types.FunctionInfo, fileContent string) []types.FunctionInfo {
+	type ranked struct {
+		function   types.FunctionInfo
+		changed    bool
+		complexity int
+	}
+
+	rankedFunctions := make([]ranked, 0, len(functions))
+	for _, function := range functions {
+		code := FunctionCode(fileContent, function)
+		rankedFunctions = append(rankedFunctions, ranked{
+			function:   function,
+			changed:    g.isChanged(function, code),
+			complexity: complexity(code),
+		})
+	}
+
+	sort.SliceStable(rankedFunctions, func(i, j int) bool {
+		a, b := rankedFunctions[i], rankedFunctions[j]
+		if a.changed != b.changed {
+			return a.changed
+		}
+		if a.complexity != b.complexity {
+			return a.complexity > b.complexity
+		}
+		return a.function.Line < b.function.Line
+	})
+
+	ordered := make([]types.FunctionInfo, 0, len(rankedFunctions))
+	for _, r := range rankedFunctions {
+		ordered = append(ordered, r.function)
+	}
+	return ordered
+}
+
+// isChanged reports whether any of the request's changed lines fall inside the function
+func (g *Generator) isChanged(function types.FunctionInfo, code string) bool {
+	endLine := function.Line + strings.Count(code, "\n")
+	for _, line := range g.options.ChangedLines {
+		if line >= function.Line && line <= endLine {
+			return true
+		}
+	}
+	return false
+}
+
+// complexity roughly measures a function's cyclomatic complexity
+func complexity(code string) int {
+	return 1 + len(branchPattern.FindAllString(code, -1))
+}
+
+// budgetExhaustedReview marks a function that was skipped because the budget ran out
+func budgetExhaustedReview(function types.FunctionInfo, style string) types.Review {
+	return types.Review{
+		ID:       function.ID,
+		Line:     function.Line,
+		Function: function.Name,
+		Style:    style,
+		Status:   types.StatusBudgetExhausted,
+	}
+}
@@ -0,0 +1,71 @@
+package review
+
+import (
+	"reviewer-bot/cache"
+	"reviewer-bot/prompt"
+	"reviewer-bot/types"
+	"strings"
+)
+
+// cacheKey returns the cache key for a function's current code and style
+func (g *Generator) cacheKey(fileContent string, function types.FunctionInfo, style string) string {
+	code := FunctionCode(fileContent, function)
+	version := prompt.Version
+	if g.anonymizer != nil {
+		// Reviews of anonymized code are kept apart from regular ones
+		version += "-anonymized"
+	}
+	return cache.Key(code, function.Language, style, g.provider.ModelName(style), version)
+}
+
+// cachedReviews stores the cached reviews of unchanged functions in reviewsByID
+// and returns the functions that still need a review
+func (g *Generator) cachedReviews(functions []types.FunctionInfo, fileContent, style string, reviewsByID map[string]types.Review) []types.FunctionInfo {
+	if g.options.Cache == nil {
+		return functions
+	}
+
+	var pending []types.FunctionInfo
+	for _, function := range functions {
+		review, ok := g.options.Cache.Get(g.cacheKey(fileContent, function, style))
+		if !ok {
+			pending = append(pending, function)
+			continue
+		}
+
+		// The function may have moved since it was cached
+		review.ID = function.ID
+		review.Line = function.Line
+		review.Function = function.Name
+		review.Style = style
+		review.Cached = true
+		if review.Score == 0 {
+			// Reviews cached before scores were added only have the stars
+			review.Score = strings.Count(review.Stars, "⭐")
+		}
+		reviewsByID[function.ID] = review
+	}
+	return pending
+}
+
+// storeReviews caches the freshly generated reviews; fallback and possibly
+// injected reviews are not cached so the next run asks the provider again
+func (g *Generator) storeReviews(functions []types.FunctionInfo, fileContent, style string, reviewsByID map[string]types.Review) {
+	if g.options.Cache == nil {
+		return
+	}
+
+	for _, function := range functions {
+		review, ok := reviewsByID[function.ID]
+		if !ok || review.Cached || review.Fallback || review.PossiblyInjected || review.Status != "" {
+			continue
+		}
+		g.options.Cache.Put(g.cacheKey(fileContent, function, style), types.Review{
+			Review:       review.Review,
+			Stars:        review.Stars,
+			Score:        review.Score,
+			SubScores:    review.SubScores,
+			RatingSource: review.RatingSource,
+		})
+	}
+}
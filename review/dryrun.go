@@ -0,0 +1,156 @@
+package review
+
+import (
+	"fmt"
+	"io"
+	"reviewer-bot/parser"
+	"reviewer-bot/prompt"
+	"reviewer-bot/provider"
+	"reviewer-bot/redact"
+	"reviewer-bot/types"
+	"strings"
+)
+
+// Plan describes the calls a review run would make, without making them
+type Plan struct {
+	File      string
+	Model     string
+	Style     string
+	Functions []PlannedFunction
+	// Batches are the batch calls tried first when the provider supports them
+	Batches []PlannedCall
+	// Individual are the per-function calls, used when batching is unavailable or fails
+	Individual []PlannedCall
+}
+
+// PlannedFunction is a parsed function and the code that would be sent for it
+type PlannedFunction struct {
+	Function  types.FunctionInfo
+	StartLine int
+	EndLine   int
+	Code      string
+	Truncated bool
+	// Redactions counts the values masked in Code, per kind
+	Redactions map[string]int
+	// InjectionWarnings describe comments and strings that try to instruct the model
+	InjectionWarnings []string
+}
+
+// PlannedCall is a single prompt with its estimated usage
+type PlannedCall struct {
+	Functions []string
+	Prompt    prompt.Prompt
+	Estimate  types.Usage
+}
+
+// Plan parses the file and builds every prompt a review run would send, with token
+// and cost estimates; it makes no provider calls
+func (g *Generator) Plan(filePath, fileContent, style string) *Plan {
+	plan := &Plan{
+		File:  filePath,
+		Model: g.provider.ModelName(style),
+		Style: style,
+	}
+
+	functions := parser.ParseFile(filePath, fileContent)
+	for _, function := range functions {
+		fullCode := FunctionCode(fileContent, function)
+		redacted := g.options.Redactor.Redact(fullCode)
+		sentCode := g.anonymizer.Code(redacted.Code, function.Language)
+		code := truncateCode(sentCode, g.options.MaxFunctionTokens)
+		plan.Functions = append(plan.Functions, PlannedFunction{
+			Function:          function,
+			StartLine:         function.Line,
+			EndLine:           function.Line + strings.Count(fullCode, "\n"),
+			Code:              code,
+			Truncated:         code != sentCode,
+			Redactions:        redacted.Counts,
+			InjectionWarnings: g.injectionWarnings(fileContent, function),
+		})
+
+		sentName := g.sentFunction(function).Name
+		plan.Individual = append(plan.Individual, PlannedCall{
+			Functions: []string{function.ID},
+			Prompt:    prompt.GetReviewPrompt(style, sentName, code),
+			Estimate:  g.estimateUsage(provider.EstimateReviewTokens(sentName, code), 1, style),
+		})
+	}
+
+	if len(functions) > 1 && g.provider.Capabilities().Batch {
+		for _, batch := range g.splitBatches(functions, fileContent) {
+			items := make([]provider.BatchItem, 0, len(batch))
+			ids := make([]string, 0, len(batch))
+			for _, function := range batch {
+				items = append(items, g.batchItem(fileContent, function))
+				ids = append(ids, function.ID)
+			}
+			plan.Batches = append(plan.Batches, PlannedCall{
+				Functions: ids,
+				Prompt:    prompt.GetBatchPrompt(items, style),
+				Estimate:  g.estimateUsage(provider.EstimateBatchTokens(items), len(items), style),
+			})
+		}
+	}
+
+	return plan
+}
+
+// Write prints the plan as a readable report
+func (p *Plan) Write(w io.Writer) {
+	fmt.Fprintf(w, "File: %s\nModel: %s\nStyle: %s\nFunctions: %d\n", p.File, p.Model, p.Style, len(p.Functions))
+
+	for i, function := range p.Functions {
+		notes := ""
+		if function.Truncated {
+			notes = ", truncated"
+		}
+		if len(function.Redactions) > 0 {
+			notes += ", redacted " + redact.Describe(function.Redactions)
+		}
+		for _, warning := range function.InjectionWarnings {
+			notes += "\n!!! " + warning
+		}
+		name := function.Function.QualifiedName
+		if name == "" {
+			name = function.Function.Name
+		}
+		fmt.Fprintf(w, "\n=== Function %d/%d: %s (%s) lines %d-%d, ~%d tokens%s\n%s\n",
+			i+1, len(p.Functions), name, function.Function.ID,
+			function.StartLine, function.EndLine, provider.EstimateTokens(function.Code), notes, function.Code)
+	}
+
+	for i, call := range p.Batches {
+		fmt.Fprintf(w, "\n=== Batch prompt %d/%d: %s\n%s\n", i+1, len(p.Batches), describeCall(call), describePrompt(call.Prompt))
+	}
+	for i, call := range p.Individual {
+		fmt.Fprintf(w, "\n=== Individual prompt %d/%d: %s\n%s\n", i+1, len(p.Individual), describeCall(call), describePrompt(call.Prompt))
+	}
+
+	fmt.Fprintf(w, "\n=== Estimate\n")
+	if len(p.Batches) > 0 {
+		fmt.Fprintf(w, "Batched:    %s\n", describeTotal(p.Batches))
+		fmt.Fprintf(w, "Fallback:   %s if every batch fails\n", describeTotal(p.Individual))
+	} else {
+		fmt.Fprintf(w, "Individual: %s\n", describeTotal(p.Individual))
+	}
+}
+
+// describeCall summarizes a planned call's functions and estimate
+func describeCall(call PlannedCall) string {
+	return fmt.Sprintf("%s, ~%d prompt + ~%d response tokens, $%.6f",
+		strings.Join(call.Functions, ", "), call.Estimate.PromptTokens, call.Estimate.ResponseTokens, call.Estimate.Cost)
+}
+
+// describePrompt labels the system and user parts of a prompt
+func describePrompt(p prompt.Prompt) string {
+	return "--- system ---\n" + p.System + "\n--- user ---\n" + p.User
+}
+
+// describeTotal sums the estimates of several calls
+func describeTotal(calls []PlannedCall) string {
+	var total types.Usage
+	for _, call := range calls {
+		total.Add(call.Estimate)
+	}
+	return fmt.Sprintf("%d calls, ~%d tokens, $%.6f", len(calls), total.TotalTokens, total.Cost)
+}
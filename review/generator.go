@@ -1,267 +1,501 @@
 package review
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"regexp"
-	"reviewer-bot/gemini"
+	"reviewer-bot/anonymize"
+	"reviewer-bot/cache"
 	"reviewer-bot/parser"
+	"reviewer-bot/provider"
+	"reviewer-bot/redact"
 	"reviewer-bot/types"
+	"reviewer-bot/usage"
+	"sort"
 	"strings"
+	"sync"
 )
 
+const (
+	// DefaultWorkers is the number of concurrent individual calls when none is configured
+	DefaultWorkers = 4
+	// DefaultBatchTokenBudget is the estimated prompt size of a single batch call
+	DefaultBatchTokenBudget = 8000
+	// DefaultMaxFunctionTokens is the estimated size above which function code is truncated
+	DefaultMaxFunctionTokens = 2000
+	// MaxFunctionLines is the number of lines of a function body kept before the
+	// rest is replaced with a truncation marker
+	MaxFunctionLines = 1000
+)
+
+// Options tunes how the generator talks to its provider
+type Options struct {
+	// Workers is the number of individual reviews generated concurrently
+	Workers int
+	// BatchTokenBudget caps the estimated prompt tokens of each batch call
+	BatchTokenBudget int
+	// MaxFunctionTokens caps the estimated tokens of a single function's code
+	MaxFunctionTokens int
+	// BatchWorkers is the number of batch calls run concurrently
+	BatchWorkers int
+	// Cache serves reviews of unchanged functions; nil disables caching
+	Cache *cache.Cache
+	// Prices is used to compute the cost of every call; nil leaves costs at zero
+	Prices usage.PriceTable
+	// Budget limits the tokens and cost spent; nil means unlimited
+	Budget *usage.Tracker
+	// ChangedLines are the edited lines of the file, reviewed first when the budget is short
+	ChangedLines []int
+	// Redactor masks secrets and personal data before code is sent; nil sends code as is
+	Redactor *redact.Redactor
+	// Anonymize renames identifiers and string literals before code is sent and maps
+	// them back in the reviews
+	Anonymize bool
+}
+
 // Generator handles the review generation process
 type Generator struct {
-	geminiClient *gemini.Client
+	provider   provider.ReviewProvider
+	options    Options
+	anonymizer *anonymize.Mapping
 }
 
-// NewGenerator creates a new review generator
-func NewGenerator(apiKey string) *Generator {
-	return &Generator{
-		geminiClient: gemini.NewClient(apiKey),
+// NewGenerator creates a new review generator backed by the given provider
+func NewGenerator(p provider.ReviewProvider, options Options) *Generator {
+	if options.Workers <= 0 {
+		options.Workers = DefaultWorkers
 	}
+	if options.BatchTokenBudget <= 0 {
+		options.BatchTokenBudget = DefaultBatchTokenBudget
+	}
+	if options.MaxFunctionTokens <= 0 {
+		options.MaxFunctionTokens = DefaultMaxFunctionTokens
+	}
+	if options.BatchWorkers <= 0 {
+		options.BatchWorkers = 1
+	}
+
+	generator := &Generator{
+		provider: p,
+		options:  options,
+	}
+	if options.Anonymize {
+		generator.anonymizer = anonymize.New()
+	}
+	return generator
 }
 
-// ExtractStarRating extracts star rating from AI response and removes stars from review text
+// ExtractStarRating extracts the star rating from a reply and returns it as star
+// emojis along with the cleaned review text, using three stars when there is none
 func ExtractStarRating(review string) (string, string) {
-	// Look for star emojis at the beginning of the review
-	starPattern := regexp.MustCompile(`^[⭐]+`)
-	if match := starPattern.FindString(review); match != "" {
-		// Remove stars from the beginning of the review
-		cleanReview := strings.TrimSpace(strings.TrimPrefix(review, match))
-		return match, cleanReview
-	}
-
-	// Fallback: count stars in the entire review
-	starCount := strings.Count(review, "⭐")
-	if starCount > 0 && starCount <= 5 {
-		stars := ""
-		for i := 0; i < starCount; i++ {
-			stars += "⭐"
-		}
-		// Remove all stars from the review text
-		cleanReview := strings.ReplaceAll(review, "⭐", "")
-		cleanReview = strings.TrimSpace(cleanReview)
-		return stars, cleanReview
+	reply, err := parseReply(review)
+	if err != nil {
+		reply = reply.repair()
 	}
-
-	// Default fallback
-	return "⭐⭐⭐", review
+	return strings.Repeat("⭐", reply.Stars), reply.Text
 }
 
-// ExtractFunctionCode extracts the function code from the file content
-func ExtractFunctionCode(content string, functionLine int) string {
+// FunctionCode returns the lines of a function, using the range found by the
+// parser when it has one and finding the end with the rules of the function's
+// language otherwise. Bodies longer than MaxFunctionLines are cut with a marker.
+func FunctionCode(content string, function types.FunctionInfo) string {
 	lines := strings.Split(content, "\n")
-
-	// Find the function start
-	startLine := functionLine - 1 // Convert to 0-based index
-
-	// Find the function end by looking for closing brace
-	endLine := startLine
-	braceCount := 0
-	inFunction := false
-
-	for i := startLine; i < len(lines); i++ {
-		line := lines[i]
-
-		if !inFunction {
-			// Look for opening brace
-			if strings.Contains(line, "{") {
-				inFunction = true
-				braceCount = strings.Count(line, "{") - strings.Count(line, "}")
-			}
-		} else {
-			braceCount += strings.Count(line, "{") - strings.Count(line, "}")
-			if braceCount <= 0 {
-				endLine = i
-				break
-			}
-		}
+	if function.Line < 1 || function.Line > len(lines) {
+		return ""
 	}
 
-	// Extract function code
-	if endLine >= startLine {
-		functionLines := lines[startLine : endLine+1]
-		return strings.Join(functionLines, "\n")
+	end := function.EndLine
+	if end < function.Line || end > len(lines) {
+		end = parser.FunctionEnd(content, function.Line, function.Language)
 	}
+	return joinFunctionLines(lines[function.Line-1 : end])
+}
 
-	// Fallback: return just the function line
-	if startLine < len(lines) {
-		return lines[startLine]
-	}
+// ExtractFunctionCode extracts the function starting on a 1-based line from the
+// file content, matching braces outside comments and strings
+func ExtractFunctionCode(content string, functionLine int) string {
+	return FunctionCode(content, types.FunctionInfo{Line: functionLine})
+}
 
-	return ""
+// joinFunctionLines joins the lines of a function, replacing those past
+// MaxFunctionLines with a truncation marker
+func joinFunctionLines(lines []string) string {
+	if len(lines) > MaxFunctionLines {
+		omitted := len(lines) - MaxFunctionLines
+		lines = append(lines[:MaxFunctionLines:MaxFunctionLines], fmt.Sprintf("... [%d more lines truncated] ...", omitted))
+	}
+	return strings.Join(lines, "\n")
 }
 
-// GenerateReviews generates reviews for all functions in a file
-func (g *Generator) GenerateReviews(filePath, fileContent, style string) (*types.ReviewResponse, error) {
+// GenerateReviews generates reviews for all functions in a file. When ctx is
+// cancelled or its deadline passes, the reviews finished so far are returned
+// and the response is marked as partial.
+func (g *Generator) GenerateReviews(ctx context.Context, filePath, fileContent, style string) (*types.ReviewResponse, error) {
 	// Parse functions from the file
 	functions := parser.ParseFile(filePath, fileContent)
 
+	response := &types.ReviewResponse{
+		File:    filePath,
+		Reviews: []types.Review{},
+		Model:   g.provider.ModelName(style),
+	}
 	if len(functions) == 0 {
-		return &types.ReviewResponse{
-			File:    filePath,
-			Reviews: []types.Review{},
-		}, nil
+		return response, nil
 	}
 
-	// If only one function, use single API call
-	if len(functions) == 1 {
-		return g.generateSingleReview(functions[0], fileContent, style)
+	reviewsByID := make(map[string]types.Review, len(functions))
+
+	// Serve unchanged functions from the cache, then spend the budget on the most
+	// important of the rest
+	pending := g.cachedReviews(functions, fileContent, style, reviewsByID)
+	pending = g.selectWithinBudget(pending, fileContent, style, reviewsByID)
+	missing := pending
+
+	// For multiple functions, try batch API calls first
+	if len(pending) > 1 && g.provider.Capabilities().Batch {
+		missing = g.collectBatches(ctx, pending, fileContent, style, reviewsByID, response)
+
+		// Re-batch only the functions the model skipped, once
+		if len(missing) > 1 && len(missing) < len(pending) && ctx.Err() == nil {
+			missing = g.collectBatches(ctx, missing, fileContent, style, reviewsByID, response)
+		}
 	}
 
-	// For multiple functions, try batch API call first, fallback to individual calls
-	reviews, err := g.generateBatchReviews(functions, fileContent, style)
-	if err != nil {
-		// Fallback to individual calls
-		return g.generateIndividualReviews(functions, fileContent, style)
+	// Request whatever is still missing one by one
+	g.generateIndividualReviews(ctx, missing, fileContent, style, reviewsByID, response)
+	g.storeReviews(pending, fileContent, style, reviewsByID)
+
+	// Leave out unfinished functions
+	for _, function := range functions {
+		review, ok := reviewsByID[function.ID]
+		if !ok {
+			response.Partial = true
+			continue
+		}
+		review.Column = function.Column
+		review.EndLine = function.EndLine
+		review.EndColumn = function.EndColumn
+		if review.Status == types.StatusBudgetExhausted {
+			response.BudgetExhausted = true
+		} else {
+			review.Redactions = g.redactions(fileContent, function)
+			review.InjectionWarnings = append(g.injectionWarnings(fileContent, function), review.InjectionWarnings...)
+		}
+		response.Reviews = append(response.Reviews, review)
 	}
 
-	return &types.ReviewResponse{
-		File:    filePath,
-		Reviews: reviews,
-	}, nil
+	// Order by line so concurrent results are deterministic
+	sort.SliceStable(response.Reviews, func(i, j int) bool {
+		return response.Reviews[i].Line < response.Reviews[j].Line
+	})
+
+	return response, nil
 }
 
-// generateSingleReview generates a review for a single function
-func (g *Generator) generateSingleReview(function types.FunctionInfo, fileContent, style string) (*types.ReviewResponse, error) {
-	functionCode := ExtractFunctionCode(fileContent, function.Line)
-	reviewText, err := g.geminiClient.GenerateReview(function.Name, functionCode, style)
-	if err != nil {
-		reviewText = g.generateFallbackReview(function.Name, style)
-	}
+// collectBatches splits the functions into batches under the token budget, requests
+// them, stores the valid reviews in reviewsByID and returns the functions that still
+// need a review
+func (g *Generator) collectBatches(ctx context.Context, functions []types.FunctionInfo, fileContent, style string, reviewsByID map[string]types.Review, response *types.ReviewResponse) []types.FunctionInfo {
+	batches := g.splitBatches(functions, fileContent)
 
-	stars, cleanReviewText := ExtractStarRating(reviewText)
-	review := types.Review{
-		Line:     function.Line,
-		Function: function.Name,
-		Style:    style,
-		Review:   cleanReviewText,
-		Stars:    stars,
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, g.options.BatchWorkers)
+
+	for _, batch := range batches {
+		if ctx.Err() != nil {
+			break
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(batch []types.FunctionInfo) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			reviews, batchErrors, callUsage, err := g.generateBatchReviews(ctx, batch, fileContent, style)
+			mu.Lock()
+			defer mu.Unlock()
+			addUsage(response, callUsage)
+			if errors.Is(err, errBudgetExhausted) {
+				// The functions are skipped one by one afterwards
+				return
+			}
+			if err != nil {
+				response.BatchErrors = append(response.BatchErrors, types.BatchError{Error: err.Error()})
+				return
+			}
+			response.BatchErrors = append(response.BatchErrors, batchErrors...)
+			for _, review := range reviews {
+				reviewsByID[review.ID] = review
+			}
+		}(batch)
 	}
+	wg.Wait()
 
-	return &types.ReviewResponse{
-		File:    "test.go", // This will be overridden
-		Reviews: []types.Review{review},
-	}, nil
+	var missing []types.FunctionInfo
+	for _, function := range functions {
+		if _, ok := reviewsByID[function.ID]; !ok {
+			missing = append(missing, function)
+		}
+	}
+	return missing
 }
 
-// generateBatchReviews attempts to generate all reviews in a single API call
-func (g *Generator) generateBatchReviews(functions []types.FunctionInfo, fileContent, style string) ([]types.Review, error) {
-	// Create a batch prompt with all functions
-	var batchPrompt strings.Builder
-	batchPrompt.WriteString(fmt.Sprintf("Review these functions in %s style. Provide one review per function:\n\n", style))
-
+// generateBatchReviews attempts to generate reviews for several functions in a single API
+// call, also returning the usage of the call, which is spent even when the reply is rejected
+func (g *Generator) generateBatchReviews(ctx context.Context, functions []types.FunctionInfo, fileContent, style string) ([]types.Review, []types.BatchError, types.Usage, error) {
+	// Send every function with its stable ID
+	items := make([]provider.BatchItem, 0, len(functions))
+	sent := make([]types.FunctionInfo, 0, len(functions))
 	for _, function := range functions {
-		functionCode := ExtractFunctionCode(fileContent, function.Line)
-		batchPrompt.WriteString(fmt.Sprintf("Function: %s\nCode:\n%s\n\n", function.Name, functionCode))
+		items = append(items, g.batchItem(fileContent, function))
+		sent = append(sent, g.sentFunction(function))
 	}
 
-	batchPrompt.WriteString("Provide reviews in this format:\n")
-	batchPrompt.WriteString("FUNCTION_NAME: ⭐⭐⭐⭐⭐ Review text here\n")
+	estimate := g.estimateUsage(provider.EstimateBatchTokens(items), len(items), style)
+	if !g.options.Budget.Reserve(estimate) {
+		return nil, nil, types.Usage{}, errBudgetExhausted
+	}
 
 	// Try batch API call
-	reviewText, err := g.geminiClient.GenerateBatchReview(batchPrompt.String(), style)
+	result, err := g.provider.GenerateBatchReview(ctx, items, style)
 	if err != nil {
-		return nil, err
+		g.options.Budget.Settle(estimate, types.Usage{})
+		return nil, nil, types.Usage{}, err
 	}
+	callUsage := g.callUsage(result, provider.EstimateBatchTokens(items), style)
+	g.options.Budget.Settle(estimate, callUsage)
 
 	// Parse batch response
-	reviews, err := g.parseBatchResponse(reviewText, functions)
+	reviews, batchErrors, err := g.parseBatchResponse(result.Text, sent, style)
 	if err != nil {
-		return nil, err
+		return nil, nil, callUsage, err
 	}
-
-	return reviews, nil
+	attributeBatchUsage(reviews, items, callUsage)
+	g.restoreBatch(reviews, batchErrors, functions)
+	return reviews, batchErrors, callUsage, nil
 }
 
-// generateIndividualReviews generates reviews one by one (fallback)
-func (g *Generator) generateIndividualReviews(functions []types.FunctionInfo, fileContent, style string) (*types.ReviewResponse, error) {
-	var reviews []types.Review
+// generateIndividualReviews generates reviews for the given functions with a bounded
+// pool of workers, storing them in reviewsByID; it stops dispatching once ctx is done
+func (g *Generator) generateIndividualReviews(ctx context.Context, functions []types.FunctionInfo, fileContent, style string, reviewsByID map[string]types.Review, response *types.ReviewResponse) {
+	if len(functions) == 0 {
+		return
+	}
 
+	jobs := make(chan types.FunctionInfo)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	workers := g.options.Workers
+	if workers > len(functions) {
+		workers = len(functions)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for function := range jobs {
+				review, err := g.generateIndividualReview(ctx, function, fileContent, style)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				reviewsByID[function.ID] = review
+				if review.Usage != nil {
+					addUsage(response, *review.Usage)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
 	for _, function := range functions {
-		functionCode := ExtractFunctionCode(fileContent, function.Line)
-		reviewText, err := g.geminiClient.GenerateReview(function.Name, functionCode, style)
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- function:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// generateIndividualReview generates a review for a single function, asking once
+// more when the reply is malformed and falling back to canned text when the
+// provider fails. It only returns an error when ctx is done.
+func (g *Generator) generateIndividualReview(ctx context.Context, function types.FunctionInfo, fileContent, style string) (types.Review, error) {
+	functionCode := g.functionCode(fileContent, function)
+	functionName := g.sentFunction(function).Name
+
+	promptTokens := provider.EstimateReviewTokens(functionName, functionCode)
+	estimate := g.estimateUsage(promptTokens, 1, style)
+
+	review := types.Review{
+		ID:       function.ID,
+		Line:     function.Line,
+		Function: function.Name,
+		Style:    style,
+	}
+
+	var reply parsedReply
+	var replyErr, callErr error
+	var subScores *types.SubScores
+	replied := false
+	rawReply := ""
+	for attempt := 1; attempt <= maxReplyAttempts; attempt++ {
+		if !g.options.Budget.Reserve(estimate) {
+			if attempt == 1 {
+				return budgetExhaustedReview(function, style), nil
+			}
+			break
+		}
+
+		result, err := g.provider.GenerateReview(ctx, functionName, functionCode, style)
 		if err != nil {
-			reviewText = g.generateFallbackReview(function.Name, style)
+			g.options.Budget.Settle(estimate, types.Usage{})
+			callErr = err
+			break
 		}
 
-		stars, cleanReviewText := ExtractStarRating(reviewText)
-		review := types.Review{
-			Line:     function.Line,
-			Function: function.Name,
-			Style:    style,
-			Review:   cleanReviewText,
-			Stars:    stars,
+		actual := g.callUsage(result, promptTokens, style)
+		g.options.Budget.Settle(estimate, actual)
+		if review.Usage == nil {
+			review.Usage = &types.Usage{}
 		}
+		review.Usage.Add(actual)
 
-		reviews = append(reviews, review)
+		replied = true
+		rawReply, subScores = splitSubScores(result.Text)
+		reply, replyErr = parseReply(g.anonymizer.Restore(rawReply))
+		if replyErr == nil {
+			break
+		}
+	}
+
+	if !replied {
+		// The provider failed before giving any reply
+		if ctx.Err() != nil {
+			return types.Review{}, ctx.Err()
+		}
+		return g.fallbackReview(review, callErr), nil
 	}
 
-	return &types.ReviewResponse{
-		File:    "test.go", // This will be overridden
-		Reviews: reviews,
-	}, nil
+	if replyErr != nil {
+		reply = reply.repair()
+		if reply.Text == "" {
+			return g.fallbackReview(review, replyErr), nil
+		}
+	}
+	review.Review = reply.Text
+	review.Stars = strings.Repeat("⭐", reply.Stars)
+	review.Score = reply.Stars
+	review.SubScores = subScores
+	review.RatingSource = reply.Source
+	flagViolation(&review, replyViolation(rawReply))
+	return review, nil
 }
 
-// parseBatchResponse parses the batch API response
-func (g *Generator) parseBatchResponse(responseText string, functions []types.FunctionInfo) ([]types.Review, error) {
-	var reviews []types.Review
+// fallbackReview fills in canned review text for a function the provider could
+// not review, keeping the usage already spent on it
+func (g *Generator) fallbackReview(review types.Review, err error) types.Review {
+	review.Review = g.generateFallbackReview(review.Function, review.Style)
+	review.Stars = strings.Repeat("⭐", defaultStars)
+	review.Score = defaultStars
+	review.RatingSource = types.RatingDefaulted
+	review.Fallback = true
+	review.Error = err.Error()
+	return review
+}
 
-	lines := strings.Split(responseText, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+// parseBatchResponse parses and validates a structured batch response, returning
+// the valid reviews in function order and an error for every rejected or missing item
+func (g *Generator) parseBatchResponse(responseText string, functions []types.FunctionInfo, style string) ([]types.Review, []types.BatchError, error) {
+	var rawItems []json.RawMessage
+	if err := json.Unmarshal([]byte(stripCodeFence(responseText)), &rawItems); err != nil {
+		return nil, nil, fmt.Errorf("batch response is not a JSON array: %v", err)
+	}
+
+	functionsByID := make(map[string]types.FunctionInfo, len(functions))
+	for _, function := range functions {
+		functionsByID[function.ID] = function
+	}
+
+	reviewsByID := make(map[string]types.Review, len(rawItems))
+	var batchErrors []types.BatchError
+
+	for i, rawItem := range rawItems {
+		var item provider.BatchReviewItem
+		decoder := json.NewDecoder(bytes.NewReader(rawItem))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&item); err != nil {
+			batchErrors = append(batchErrors, types.BatchError{Error: fmt.Sprintf("item %d is invalid: %v", i, err)})
 			continue
 		}
 
-		// Look for pattern: FUNCTION_NAME: ⭐⭐⭐⭐⭐ Review text
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
+		function, known := functionsByID[item.ID]
+		reviewText := cleanReviewText(strings.ReplaceAll(item.Review, "⭐", ""))
+
+		var itemError string
+		switch {
+		case !known:
+			itemError = fmt.Sprintf("item %d has unknown function ID %q", i, item.ID)
+		case reviewsByID[item.ID].Function != "":
+			itemError = "duplicate review for function"
+		case item.Stars < 1 || item.Stars > 5:
+			itemError = fmt.Sprintf("star rating %d is outside 1-5", item.Stars)
+		case checkReviewText(reviewText) != nil:
+			itemError = checkReviewText(reviewText).Error()
+		}
+		if itemError != "" {
+			batchErrors = append(batchErrors, types.BatchError{ID: item.ID, Function: function.Name, Error: itemError})
 			continue
 		}
 
-		functionName := strings.TrimSpace(parts[0])
-		reviewPart := strings.TrimSpace(parts[1])
-
-		// Handle mock responses with generic function names (Function1, Function2, etc.)
-		if strings.HasPrefix(functionName, "Function") {
-			// Extract the number from FunctionX
-			var functionIndex int
-			fmt.Sscanf(functionName, "Function%d", &functionIndex)
-
-			// Map to actual function by index (1-based to 0-based)
-			if functionIndex > 0 && functionIndex <= len(functions) {
-				actualFunction := functions[functionIndex-1]
-				stars, cleanReviewText := ExtractStarRating(reviewPart)
-				review := types.Review{
-					Line:     actualFunction.Line,
-					Function: actualFunction.Name,
-					Style:    "funny", // Default style
-					Review:   cleanReviewText,
-					Stars:    stars,
-				}
-				reviews = append(reviews, review)
-			}
-		} else {
-			// Find matching function by name
-			for _, function := range functions {
-				if function.Name == functionName {
-					stars, cleanReviewText := ExtractStarRating(reviewPart)
-					review := types.Review{
-						Line:     function.Line,
-						Function: function.Name,
-						Style:    "funny", // Default style
-						Review:   cleanReviewText,
-						Stars:    stars,
-					}
-					reviews = append(reviews, review)
-					break
-				}
-			}
+		review := types.Review{
+			ID:           function.ID,
+			Line:         function.Line,
+			Function:     function.Name,
+			Style:        style,
+			Review:       reviewText,
+			Stars:        strings.Repeat("⭐", item.Stars),
+			Score:        item.Stars,
+			SubScores:    validSubScores(item.Scores),
+			RatingSource: types.RatingParsed,
+		}
+		flagViolation(&review, reviewViolation(item.Review))
+		reviewsByID[item.ID] = review
+	}
+
+	// Keep the order of the parsed functions and report any the model skipped
+	var reviews []types.Review
+	for _, function := range functions {
+		review, ok := reviewsByID[function.ID]
+		if !ok {
+			batchErrors = append(batchErrors, types.BatchError{ID: function.ID, Function: function.Name, Error: "missing from batch response"})
+			continue
 		}
+		reviews = append(reviews, review)
 	}
 
-	return reviews, nil
+	return reviews, batchErrors, nil
+}
+
+// stripCodeFence removes a surrounding markdown code fence from a model reply
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+
+	text = strings.TrimPrefix(text, "```")
+	if newline := strings.Index(text, "\n"); newline >= 0 {
+		text = text[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
 }
 
 // generateFallbackReview generates a fallback review when Gemini is unavailable
@@ -0,0 +1,92 @@
+package review
+
+import (
+	"fmt"
+	"regexp"
+	"reviewer-bot/lexer"
+	"reviewer-bot/types"
+	"strings"
+	"unicode/utf8"
+)
+
+// injectionPatterns match text written to instruct a model rather than a reader
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,30}\b(instructions?|prompts?|rules|directions)\b`),
+	regexp.MustCompile(`(?i)\bnew instructions?\b`),
+	regexp.MustCompile(`(?i)\byou are (now|no longer)\b`),
+	regexp.MustCompile(`(?i)\b(system|developer) (prompt|instruction|message)s?\b`),
+	regexp.MustCompile(`(?i)\b(give|rate|award|score)\b.{0,30}\b(5|five|max(imum)?|full)\s*(stars?|⭐)`),
+	regexp.MustCompile(`(?i)\b(as an ai|language model)\b`),
+	regexp.MustCompile(`(?i)</?\s*(system|assistant|instructions?)\s*>`),
+	regexp.MustCompile(`(?im)^\W*(system|assistant)\s*:`),
+	regexp.MustCompile(`<<<\s*(END )?CODE\b`),
+}
+
+// injectionWarnings scans the comments and string literals of a function for text
+// that tries to instruct the model, returning one warning per suspicious passage
+func (g *Generator) injectionWarnings(fileContent string, function types.FunctionInfo) []string {
+	code := FunctionCode(fileContent, function)
+
+	var warnings []string
+	for _, segment := range lexer.Split(code, function.Language) {
+		if segment.Kind == lexer.Code {
+			continue
+		}
+		for _, pattern := range injectionPatterns {
+			match := pattern.FindStringIndex(segment.Text)
+			if match == nil {
+				continue
+			}
+
+			kind := "comment"
+			if segment.Kind == lexer.String {
+				kind = "string"
+			}
+			line := function.Line + strings.Count(code[:segment.Offset+match[0]], "\n")
+			text := strings.TrimSpace(segment.Text[match[0]:match[1]])
+			warnings = append(warnings, fmt.Sprintf("%s on line %d looks like an instruction to the reviewer: %q", kind, line, text))
+			break
+		}
+	}
+	return warnings
+}
+
+// replyViolation describes how a single review reply breaks the requested
+// "stars then one-liner" format, or returns "" when it conforms. Replies without
+// leading stars are left to the reply validator.
+func replyViolation(reply string) string {
+	reply = strings.TrimSpace(reply)
+	stars := 0
+	for strings.HasPrefix(reply[stars*len("⭐"):], "⭐") {
+		stars++
+	}
+
+	if stars > 5 {
+		return fmt.Sprintf("reply has %d stars", stars)
+	}
+	return reviewViolation(strings.TrimSpace(reply[stars*len("⭐"):]))
+}
+
+// reviewViolation describes how review text breaks the requested one-liner
+// format, or returns "" when it conforms
+func reviewViolation(text string) string {
+	switch {
+	case strings.Contains(strings.TrimSpace(text), "\n"):
+		return "reply has more than one line"
+	case utf8.RuneCountInString(text) > suspiciousReviewLength:
+		return fmt.Sprintf("reply is %d characters long", utf8.RuneCountInString(text))
+	case strings.Contains(text, "<<<"):
+		return "reply repeats the code markers"
+	}
+	return ""
+}
+
+// flagViolation marks a review whose reply broke the requested format as
+// possibly following instructions injected through the code
+func flagViolation(review *types.Review, violation string) {
+	if violation == "" {
+		return
+	}
+	review.PossiblyInjected = true
+	review.InjectionWarnings = append(review.InjectionWarnings, violation)
+}
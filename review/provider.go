@@ -0,0 +1,96 @@
+package review
+
+import (
+	"fmt"
+	"reviewer-bot/audit"
+	"reviewer-bot/config"
+	"reviewer-bot/gemini"
+	"reviewer-bot/ollama"
+	"reviewer-bot/openai"
+	"reviewer-bot/provider"
+	"time"
+)
+
+// NewProvider creates the review provider selected by the configuration, wrapped
+// with an audit log, a shared rate limiter, retries and a circuit breaker
+func NewProvider(cfg *config.Config) (provider.ReviewProvider, error) {
+	backend, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Log every attempt, since each one sends code off the machine; mock reviews
+	// never leave it
+	if _, mock := backend.(*provider.MockProvider); !mock && !cfg.Audit.Disabled {
+		logger := audit.NewLogger(cfg.Audit.Path, cfg.Audit.MaxBytes, cfg.Audit.MaxFiles)
+		backend = audit.NewProvider(backend, logger, cfg.Audit.FullPrompt)
+	}
+
+	// Every retry attempt waits for the rate limiter
+	return provider.NewRetryingProvider(
+		backend,
+		provider.RetryPolicy{
+			MaxRetries:  cfg.MaxRetries,
+			BaseDelay:   cfg.RetryBaseDelay,
+			MaxDelay:    cfg.RetryMaxDelay,
+			CallTimeout: callTimeout(cfg),
+		},
+		provider.NewCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		provider.NewRateLimiter(cfg.RequestsPerMinute, cfg.TokensPerMinute),
+	), nil
+}
+
+// callTimeout returns the timeout of a single attempt: the configured one, or
+// the default for Gemini, which has no request timeout of its own
+func callTimeout(cfg *config.Config) time.Duration {
+	if cfg.CallTimeout > 0 || cfg.Provider != config.ProviderGemini {
+		return cfg.CallTimeout
+	}
+	return config.DefaultCallTimeout
+}
+
+// newBackend creates the bare review provider selected by the configuration
+func newBackend(cfg *config.Config) (provider.ReviewProvider, error) {
+	if cfg.MockMode {
+		return provider.NewMockProvider(), nil
+	}
+
+	switch cfg.Provider {
+	case config.ProviderGemini:
+		// Fall back to mock reviews when no API key is available
+		if cfg.APIKey == "" && cfg.Gemini.Backend != gemini.BackendVertex {
+			return provider.NewMockProvider(), nil
+		}
+		return gemini.NewClient(gemini.Config{
+			APIKey:     cfg.APIKey,
+			Backend:    cfg.Gemini.Backend,
+			Project:    cfg.Gemini.Project,
+			Location:   cfg.Gemini.Location,
+			BaseURL:    cfg.Gemini.BaseURL,
+			Generation: cfg.Gemini.Generation,
+			Styles:     cfg.Gemini.Styles,
+			Request:    cfg.Request,
+		}), nil
+	case config.ProviderOpenAI:
+		return openai.NewClient(openai.Config{
+			BaseURL:           cfg.OpenAI.BaseURL,
+			APIKey:            cfg.OpenAI.APIKey,
+			Model:             cfg.OpenAI.Model,
+			Timeout:           cfg.OpenAI.Timeout,
+			AuthHeader:        cfg.OpenAI.AuthHeader,
+			AuthScheme:        cfg.OpenAI.AuthScheme,
+			DisableJSONSchema: cfg.OpenAI.DisableJSONSchema,
+		}), nil
+	case config.ProviderOllama:
+		return ollama.NewClient(ollama.Config{
+			Host:             cfg.Ollama.Host,
+			Model:            cfg.Ollama.Model,
+			Timeout:          cfg.Ollama.Timeout,
+			DisableStreaming: cfg.Ollama.DisableStreaming,
+		}), nil
+	case config.ProviderMock:
+		return provider.NewMockProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown review provider: %s", cfg.Provider)
+	}
+}
@@ -0,0 +1,60 @@
+package review
+
+import (
+	"regexp"
+	"reviewer-bot/types"
+	"strconv"
+	"strings"
+)
+
+var (
+	// scoresLinePattern matches the optional sub-scores line of a single review reply
+	scoresLinePattern = regexp.MustCompile(`(?im)^[^\w\n]*scores?[^\S\n]*:([^\n]*)$`)
+	// subScorePattern matches one "name=N" entry of a sub-scores line
+	subScorePattern = regexp.MustCompile(`(?i)\b(readability|correctness[ _]risk|complexity|error[ _]handling|naming)[^\S\n]*[=:][^\S\n]*(\d+)`)
+)
+
+// splitSubScores removes the sub-scores line from a single review reply and
+// returns the rest of the reply along with the valid scores
+func splitSubScores(reply string) (string, *types.SubScores) {
+	match := scoresLinePattern.FindStringSubmatchIndex(reply)
+	if match == nil {
+		return reply, nil
+	}
+
+	var scores types.SubScores
+	for _, entry := range subScorePattern.FindAllStringSubmatch(reply[match[2]:match[3]], -1) {
+		value, _ := strconv.Atoi(entry[2])
+		switch strings.NewReplacer(" ", "_").Replace(strings.ToLower(entry[1])) {
+		case "readability":
+			scores.Readability = value
+		case "correctness_risk":
+			scores.CorrectnessRisk = value
+		case "complexity":
+			scores.Complexity = value
+		case "error_handling":
+			scores.ErrorHandling = value
+		case "naming":
+			scores.Naming = value
+		}
+	}
+	return strings.TrimSpace(reply[:match[0]] + reply[match[1]:]), validSubScores(&scores)
+}
+
+// validSubScores drops the sub-scores outside 1-5, returning nil when none are left
+func validSubScores(scores *types.SubScores) *types.SubScores {
+	if scores == nil {
+		return nil
+	}
+
+	valid := *scores
+	for _, score := range []*int{&valid.Readability, &valid.CorrectnessRisk, &valid.Complexity, &valid.ErrorHandling, &valid.Naming} {
+		if *score < 1 || *score > 5 {
+			*score = 0
+		}
+	}
+	if valid == (types.SubScores{}) {
+		return nil
+	}
+	return &valid
+}
@@ -0,0 +1,67 @@
+package review
+
+import (
+	"reviewer-bot/provider"
+	"reviewer-bot/types"
+)
+
+// callUsage completes the usage of a successful call: token counts are estimated
+// when the backend reported none, and the cost is priced for the model of the style
+func (g *Generator) callUsage(result provider.Response, promptTokens int, style string) types.Usage {
+	callUsage := result.Usage
+	if callUsage.PromptTokens == 0 && callUsage.ResponseTokens == 0 {
+		callUsage = types.Usage{
+			PromptTokens:   promptTokens,
+			ResponseTokens: provider.EstimateTokens(result.Text),
+			Estimated:      true,
+		}
+	}
+	callUsage.TotalTokens = callUsage.PromptTokens + callUsage.ResponseTokens
+	callUsage.Cost = g.options.Prices.Cost(g.provider.ModelName(style), callUsage)
+	return callUsage
+}
+
+// attributeBatchUsage splits a batch call's usage between the accepted reviews in
+// proportion to the estimated size of each function
+func attributeBatchUsage(reviews []types.Review, items []provider.BatchItem, callUsage types.Usage) {
+	weights := make(map[string]int, len(items))
+	for _, item := range items {
+		weights[item.ID] = provider.EstimateBatchItemTokens(item)
+	}
+
+	totalWeight := 0
+	for _, review := range reviews {
+		totalWeight += weights[review.ID]
+	}
+	if totalWeight == 0 {
+		return
+	}
+
+	remaining := callUsage
+	for i := range reviews {
+		share := callUsage
+		if i < len(reviews)-1 {
+			fraction := float64(weights[reviews[i].ID]) / float64(totalWeight)
+			share.PromptTokens = int(float64(callUsage.PromptTokens) * fraction)
+			share.ResponseTokens = int(float64(callUsage.ResponseTokens) * fraction)
+			share.Cost = callUsage.Cost * fraction
+		} else {
+			// The last review takes the rounding remainder
+			share = remaining
+		}
+		share.TotalTokens = share.PromptTokens + share.ResponseTokens
+
+		remaining.PromptTokens -= share.PromptTokens
+		remaining.ResponseTokens -= share.ResponseTokens
+		remaining.Cost -= share.Cost
+		reviews[i].Usage = &share
+	}
+}
+
+// addUsage adds a call's usage to the run total
+func addUsage(response *types.ReviewResponse, callUsage types.Usage) {
+	if response.Usage == nil {
+		response.Usage = &types.Usage{}
+	}
+	response.Usage.Add(callUsage)
+}
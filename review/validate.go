@@ -0,0 +1,205 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"reviewer-bot/types"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxReviewLength is the review length in characters the prompts ask for;
+	// longer replies are requested again and then shortened
+	maxReviewLength = 100
+	// suspiciousReviewLength is the length above which a reply is also flagged as
+	// possibly injected: models overshoot maxReviewLength a little on their own,
+	// but a reply this long has usually stopped following the prompt
+	suspiciousReviewLength = maxReviewLength * 8 / 5
+	// defaultStars is the rating used when a reply has none
+	defaultStars = 3
+	// maxReplyAttempts is how often a review is requested before a malformed reply
+	// is repaired instead
+	maxReplyAttempts = 2
+)
+
+// starRatingExpr matches a rating given as star emojis or black stars
+const starRatingExpr = `((?:⭐\x{FE0F}?)+)|(★+)☆*`
+
+// ratingExpr matches a rating given as stars, "4/5", "4 out of 5" or "4 stars"
+const ratingExpr = `(?i:` + starRatingExpr + `|\b(\d+(?:\.\d+)?)\s*(?:/|out of)\s*5\b|\b(\d+)\s*(?:stars?\b|⭐))`
+
+var (
+	// leadingRatingPattern matches a rating at the start of a reply, optionally labelled
+	leadingRatingPattern = regexp.MustCompile(`^(?i:rating\s*[:=]?\s*)?` + ratingExpr)
+	// ratingPattern matches a rating anywhere in a reply
+	ratingPattern = regexp.MustCompile(ratingExpr)
+	// starRatingPattern matches a rating given as stars anywhere in a reply
+	starRatingPattern = regexp.MustCompile(starRatingExpr)
+	// reviewLabelPattern matches a "Review:" label in front of the review text
+	reviewLabelPattern = regexp.MustCompile(`^(?i)review\s*[:\-]\s*`)
+	// emptyBracketsPattern matches brackets left empty once a rating is taken out
+	emptyBracketsPattern = regexp.MustCompile(`\(\s*\)|\[\s*\]`)
+	// listMarkerPattern matches markdown headings, quotes and list markers
+	listMarkerPattern = regexp.MustCompile(`^(?:#+|>|[-*+]|\d+\.)\s+`)
+)
+
+// parsedReply is a review reply split into its star rating and text
+type parsedReply struct {
+	Stars int
+	Text  string
+	// Source is one of the types.Rating* values
+	Source string
+}
+
+// parseReply extracts the star rating and review text from a single review reply,
+// stripping markdown and quotes. The error describes why the reply is malformed,
+// in which case the result holds whatever could be recovered.
+func parseReply(reply string) (parsedReply, error) {
+	text := cleanReviewText(reply)
+
+	var result parsedReply
+	if match := leadingRatingPattern.FindStringSubmatchIndex(text); match != nil {
+		result.Stars = ratingValue(text, match)
+		result.Source = types.RatingParsed
+		text = text[match[1]:]
+	} else if match := findRating(text); match != nil {
+		result.Stars = ratingValue(text, match)
+		result.Source = types.RatingInferred
+		text = text[:match[0]] + " " + text[match[1]:]
+	}
+	result.Text = trimReviewText(text)
+
+	switch {
+	case result.Source == "":
+		return result, errors.New("reply has no star rating")
+	case result.Stars < 1 || result.Stars > 5:
+		return result, fmt.Errorf("star rating %d is outside 1-5", result.Stars)
+	}
+	return result, checkReviewText(result.Text)
+}
+
+// repair makes a malformed reply usable: a missing rating becomes the default,
+// an out of range one is clamped and overlong text is shortened
+func (r parsedReply) repair() parsedReply {
+	switch {
+	case r.Source == "":
+		r.Stars = defaultStars
+		r.Source = types.RatingDefaulted
+	case r.Stars < 1:
+		r.Stars = 1
+	case r.Stars > 5:
+		r.Stars = 5
+	}
+	r.Text = shortenReview(r.Text, maxReviewLength)
+	return r
+}
+
+// findRating returns the submatch indexes of a rating anywhere in text. Stars win
+// over a number found before them, which may belong to the review as in
+// "O(2/5) time ⭐⭐⭐", unless the number counts those stars as in "4 ⭐".
+func findRating(text string) []int {
+	match := ratingPattern.FindStringSubmatchIndex(text)
+	stars := starRatingPattern.FindStringSubmatchIndex(text)
+	if match != nil && stars != nil && match[1] <= stars[0] {
+		return stars
+	}
+	return match
+}
+
+// ratingValue converts a rating match into a number of stars
+func ratingValue(text string, match []int) int {
+	group := func(n int) string {
+		if 2*n >= len(match) || match[2*n] < 0 {
+			return ""
+		}
+		return text[match[2*n]:match[2*n+1]]
+	}
+
+	switch {
+	case group(1) != "":
+		return strings.Count(group(1), "⭐")
+	case group(2) != "":
+		return utf8.RuneCountInString(group(2))
+	case group(3) != "":
+		value, _ := strconv.ParseFloat(group(3), 64)
+		return int(math.Round(value))
+	default:
+		value, _ := strconv.Atoi(group(4))
+		return value
+	}
+}
+
+// cleanReviewText joins the lines of a reply and strips code fences, markdown
+// markers and surrounding quotes
+func cleanReviewText(text string) string {
+	var lines []string
+	for _, line := range strings.Split(stripCodeFence(text), "\n") {
+		line = strings.TrimSpace(listMarkerPattern.ReplaceAllString(strings.TrimSpace(line), ""))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	text = strings.Join(lines, " ")
+	text = strings.NewReplacer("**", "", "__", "", "`", "").Replace(text)
+	return trimQuotes(text)
+}
+
+// trimReviewText removes the separators and labels left around review text once
+// the rating is taken out
+func trimReviewText(text string) string {
+	text = emptyBracketsPattern.ReplaceAllString(text, "")
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.TrimRight(strings.TrimLeft(text, " -–—:|.,"), " -–—:|,")
+	text = reviewLabelPattern.ReplaceAllString(text, "")
+	return trimQuotes(text)
+}
+
+// trimQuotes removes quotes and emphasis markers wrapped around the whole text
+func trimQuotes(text string) string {
+	for {
+		text = strings.TrimSpace(text)
+		trimmed := false
+		for _, pair := range [][2]string{{`"`, `"`}, {"'", "'"}, {"“", "”"}, {"‘", "’"}, {"*", "*"}, {"_", "_"}} {
+			if len(text) > len(pair[0])+len(pair[1]) && strings.HasPrefix(text, pair[0]) && strings.HasSuffix(text, pair[1]) {
+				text = text[len(pair[0]) : len(text)-len(pair[1])]
+				trimmed = true
+			}
+		}
+		if !trimmed {
+			return text
+		}
+	}
+}
+
+// checkReviewText returns an error when review text is empty or longer than
+// the prompts allow
+func checkReviewText(text string) error {
+	length := utf8.RuneCountInString(text)
+	switch {
+	case length == 0:
+		return errors.New("review text is empty")
+	case length > maxReviewLength:
+		return fmt.Errorf("review is %d characters, over the %d character limit", length, maxReviewLength)
+	}
+	return nil
+}
+
+// shortenReview cuts text longer than limit characters at a word boundary and
+// marks the cut with an ellipsis
+func shortenReview(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	cut := string(runes[:limit-1])
+	if space := strings.LastIndex(cut, " "); space > len(cut)/2 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,;:-") + "…"
+}
@@ -0,0 +1,141 @@
+package review
+
+import (
+	"reviewer-bot/types"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    parsedReply
+		wantErr bool
+	}{
+		{
+			name:  "leading stars",
+			reply: "⭐⭐⭐⭐ Clear and well named.",
+			want:  parsedReply{Stars: 4, Text: "Clear and well named.", Source: types.RatingParsed},
+		},
+		{
+			name:  "labelled numeric rating",
+			reply: "**Rating: 2/5** - Review: \"Too many branches.\"",
+			want:  parsedReply{Stars: 2, Text: "Too many branches.", Source: types.RatingParsed},
+		},
+		{
+			name:  "black stars in a code fence",
+			reply: "```\n★★★★★☆\nNothing to add.\n```",
+			want:  parsedReply{Stars: 5, Text: "Nothing to add.", Source: types.RatingParsed},
+		},
+		{
+			name:  "trailing rating",
+			reply: "Solid error handling (4 out of 5)",
+			want:  parsedReply{Stars: 4, Text: "Solid error handling", Source: types.RatingInferred},
+		},
+		{
+			name:  "stars win over a fraction in the text",
+			reply: "Runs in O(2/5) time ⭐⭐⭐",
+			want:  parsedReply{Stars: 3, Text: "Runs in O(2/5) time", Source: types.RatingInferred},
+		},
+		{
+			name:  "number counting stars",
+			reply: "Nice loop, 4 ⭐",
+			want:  parsedReply{Stars: 4, Text: "Nice loop", Source: types.RatingInferred},
+		},
+		{
+			name:    "no rating",
+			reply:   "Looks fine to me.",
+			want:    parsedReply{Text: "Looks fine to me."},
+			wantErr: true,
+		},
+		{
+			name:    "rating out of range",
+			reply:   "7/5 Perfect.",
+			want:    parsedReply{Stars: 7, Text: "Perfect.", Source: types.RatingParsed},
+			wantErr: true,
+		},
+		{
+			name:    "empty text",
+			reply:   "⭐⭐",
+			want:    parsedReply{Stars: 2, Source: types.RatingParsed},
+			wantErr: true,
+		},
+		{
+			name:    "text over the limit",
+			reply:   "⭐⭐ " + strings.Repeat("word ", 30),
+			want:    parsedReply{Stars: 2, Text: strings.TrimSpace(strings.Repeat("word ", 30)), Source: types.RatingParsed},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReply(tt.reply)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseReply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseReply() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepair(t *testing.T) {
+	long := strings.Repeat("word ", 30)
+	tests := []struct {
+		name  string
+		reply parsedReply
+		want  parsedReply
+	}{
+		{
+			name:  "missing rating",
+			reply: parsedReply{Text: "Fine."},
+			want:  parsedReply{Stars: defaultStars, Text: "Fine.", Source: types.RatingDefaulted},
+		},
+		{
+			name:  "rating above range",
+			reply: parsedReply{Stars: 7, Text: "Fine.", Source: types.RatingParsed},
+			want:  parsedReply{Stars: 5, Text: "Fine.", Source: types.RatingParsed},
+		},
+		{
+			name:  "rating below range",
+			reply: parsedReply{Stars: 0, Text: "Fine.", Source: types.RatingInferred},
+			want:  parsedReply{Stars: 1, Text: "Fine.", Source: types.RatingInferred},
+		},
+		{
+			name:  "overlong text",
+			reply: parsedReply{Stars: 3, Text: long, Source: types.RatingParsed},
+			want:  parsedReply{Stars: 3, Text: shortenReview(long, maxReviewLength), Source: types.RatingParsed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.reply.repair(); got != tt.want {
+				t.Errorf("repair() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortenReview(t *testing.T) {
+	if got := shortenReview("Short enough.", 20); got != "Short enough." {
+		t.Errorf("shortenReview() = %q, want the text unchanged", got)
+	}
+
+	got := shortenReview("The function reads well, but the loop allocates on every pass.", 30)
+	if got != "The function reads well, but…" {
+		t.Errorf("shortenReview() = %q, want a cut at a word boundary", got)
+	}
+	if length := utf8.RuneCountInString(got); length > 30 {
+		t.Errorf("shortenReview() is %d characters, over the limit of 30", length)
+	}
+
+	unbroken := shortenReview(strings.Repeat("x", 50), 10)
+	if unbroken != strings.Repeat("x", 9)+"…" {
+		t.Errorf("shortenReview() = %q, want nine characters and an ellipsis", unbroken)
+	}
+}
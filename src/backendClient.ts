@@ -4,6 +4,10 @@ import { ReviewRequest, ReviewResponse, ErrorResponse, ExtensionConfig } from '.
 import * as fs from 'fs';
 import * as path from 'path';
 
+// Exit code of a run that skipped functions because the spending budget ran out;
+// its output still holds the finished reviews
+const BUDGET_EXHAUSTED_EXIT_CODE = 3;
+
 export class BackendClient {
     private config: ExtensionConfig;
     private backendPath: string;
@@ -19,7 +23,7 @@ export class BackendClient {
             apiKey: config.get('apiKey', ''),
             reviewStyle: config.get('reviewStyle', 'funny'),
             autoGenerateOnSave: config.get('autoGenerateOnSave', false),
-            enabledLanguages: config.get('enabledLanguages', ['go', 'javascript', 'typescript', 'python', 'dart'])
+            enabledLanguages: config.get('enabledLanguages', ['go', 'javascript', 'typescript', 'python', 'dart', 'c', 'cpp', 'java'])
         };
     }
 
@@ -45,13 +49,11 @@ export class BackendClient {
         return new Promise((resolve, reject) => {
             // Set environment variables
             const env = { ...process.env };
+            // The backend falls back to mock reviews by itself when the Gemini API
+            // key backend has no key; other providers and Vertex AI need none
             if (this.config.apiKey) {
                 env.GEMINI_API_KEY = this.config.apiKey;
             }
-            // Enable mock mode if no API key is provided
-            if (!this.config.apiKey) {
-                env.MOCK_MODE = 'true';
-            }
 
             // Check if Go executable exists
             if (!fs.existsSync(this.backendPath)) {
@@ -80,7 +82,7 @@ export class BackendClient {
 
             // Handle process completion
             goProcess.on('close', (code) => {
-                if (code !== 0) {
+                if (code !== 0 && code !== BUDGET_EXHAUSTED_EXIT_CODE) {
                     if (stderr.includes('Failed to parse JSON')) {
                         reject(new Error(`Invalid request format: ${stderr}`));
                     } else if (stderr.includes('Missing required fields')) {
@@ -134,7 +136,10 @@ export class BackendClient {
             'javascript': 'javascript',
             'typescript': 'typescript',
             'python': 'python',
-            'dart': 'dart'
+            'dart': 'dart',
+            'c': 'c',
+            'cpp': 'cpp',
+            'java': 'java'
         };
 
         const mappedLanguage = languageMap[languageId];
@@ -88,13 +88,23 @@ export class ReviewCodeLensProvider implements vscode.CodeLensProvider {
         console.log(`CodeLens: Language ID: ${document.languageId}`);
         console.log(`CodeLens: Found ${fileReviews.length} reviews for this file`);
     
-        // Map line numbers to reviews
-        const reviewsByLine = new Map<number, Review>();
+        // Place lenses where the backend found each function, as long as the
+        // line still names it
+        const reviewedLines = new Set<number>();
+        const placedReviews = new Set<Review>();
         for (const review of fileReviews) {
-            reviewsByLine.set(review.line, review);
-            console.log(`CodeLens: Available review for '${review.function}' at line ${review.line}: ${review.stars} ${review.review}`);
+            const line = this.reviewLine(document, review);
+            if (line === undefined || reviewedLines.has(line)) {
+                continue;
+            }
+            console.log(`CodeLens: Placing review for '${review.function}' at line ${line}: ${review.stars} ${review.review}`);
+            reviewedLines.add(line);
+            placedReviews.add(review);
+            lenses.push(this.createLens(line, review.function, this.reviewTitle(review)));
         }
     
+        // Fall back to patterns for functions whose reviews could not be placed,
+        // for example when the file changed since it was reviewed
         const patterns = this.getFunctionPatterns(document.languageId);
         console.log(`CodeLens: Using ${patterns.length} patterns for language ${document.languageId}`);
     
@@ -121,6 +131,9 @@ export class ReviewCodeLensProvider implements vscode.CodeLensProvider {
                 }
     
                 const functionKey = `${functionName}:${functionLine}`;
+                if (reviewedLines.has(functionLine)) {
+                    continue;
+                }
                 if (processedFunctions.has(functionKey)) {
                     console.log(`CodeLens: Skipping duplicate function '${functionName}' at line ${functionLine}`);
                     continue;
@@ -129,35 +142,65 @@ export class ReviewCodeLensProvider implements vscode.CodeLensProvider {
                 processedFunctions.add(functionKey);
                 console.log(`CodeLens: Found function '${functionName}' at line ${functionLine}`);
     
-                const range = new vscode.Range(functionLine, 0, functionLine, 0);
-                const review = reviewsByLine.get(functionLine);
-    
                 let title = '';
-                if (review) {
-                    console.log(`CodeLens: Found review for function '${functionName}' at line ${functionLine}`);
-                    title = `${review.stars} ${review.review}`;
+                const reviewByName = fileReviews.find(r => r.function === functionName && !placedReviews.has(r));
+                if (reviewByName) {
+                    console.log(`CodeLens: Found review by name '${functionName}' at line ${reviewByName.line}`);
+                    placedReviews.add(reviewByName);
+                    title = this.reviewTitle(reviewByName);
                 } else {
                     console.log(`CodeLens: No review found for function '${functionName}' at line ${functionLine}`);
-                    const reviewByName = fileReviews.find(r => r.function === functionName);
-                    if (reviewByName) {
-                        console.log(`CodeLens: Found review by name '${functionName}' at line ${reviewByName.line}`);
-                        title = `${reviewByName.stars} ${reviewByName.review}`;
-                    } else {
-                        title = this.generateDefaultReview(functionName);
-                    }
+                    title = this.generateDefaultReview(functionName);
                 }
     
-                lenses.push(new vscode.CodeLens(range, {
-                    title: title,
-                    command: 'reviewer-bot.showReviewHistory',
-                    arguments: [functionName],
-                    tooltip: `Review for ${functionName}`
-                }));
+                lenses.push(this.createLens(functionLine, functionName, title));
             }
         }
     
         return lenses;
     }
+
+    // reviewLine returns the zero-based line of a review's function, or undefined
+    // when the line the backend reported no longer names the function
+    private reviewLine(document: vscode.TextDocument, review: Review): number | undefined {
+        const line = review.line - 1;
+        if (!review.function || line < 0 || line >= document.lineCount) {
+            return undefined;
+        }
+        // Qualified names such as Foo::bar appear unqualified in class bodies
+        const name = review.function.split('::').pop() || review.function;
+        return document.lineAt(line).text.includes(name) ? line : undefined;
+    }
+
+    private reviewTitle(review: Review): string {
+        if (review.status === 'budget_exhausted') {
+            return '💸 Not reviewed: spending budget exhausted';
+        }
+        const title = `${review.stars} ${review.review}`;
+        return review.possibly_injected ? `⚠️ ${title}` : title;
+    }
+
+    private createLens(line: number, functionName: string, title: string): vscode.CodeLens {
+        return new vscode.CodeLens(new vscode.Range(line, 0, line, 0), {
+            title: title,
+            command: 'reviewer-bot.showReviewHistory',
+            arguments: [functionName],
+            tooltip: `Review for ${functionName}`
+        });
+    }
+
+    // declaredName returns a pattern's captured name unless the match is a control
+    // statement or an expression such as "new Foo() {"
+    private declaredName(match: RegExpExecArray): string | null {
+        const name = match[1];
+        if (!name || /^(if|for|while|switch|catch|return|sizeof|synchronized)$/.test(name)) {
+            return null;
+        }
+        if (/\b(new|return|throw|else)\s/.test(match[0].slice(0, match[0].indexOf(name)))) {
+            return null;
+        }
+        return name.split('::').pop() || null;
+    }
     
 
     private getFunctionPatterns(languageId: string): Array<{regex: string, extractName: (match: RegExpExecArray) => string | null}> {
@@ -199,28 +242,18 @@ export class ReviewCodeLensProvider implements vscode.CodeLensProvider {
                 }];
 
             case 'c':
-                return [
-                    {
-                        regex: `^[a-zA-Z_][a-zA-Z0-9_]*\\s+([a-zA-Z_][a-zA-Z0-9_]*)\\s*\\([^)]*\\)\\s*\\{`,
-                        extractName: (match) => match[1] || null
-                    },
-                    {
-                        regex: `^([a-zA-Z_][a-zA-Z0-9_]*)\\s*\\([^)]*\\)\\s*\\{`,
-                        extractName: (match) => match[1] || null
-                    }
-                ];
+                return [{
+                    // Optional storage classes, qualifiers and return type, pointers included
+                    regex: `^[ \\t]*(?:[a-zA-Z_][a-zA-Z0-9_]*[ \\t*]+)*\\**([a-zA-Z_][a-zA-Z0-9_]*)\\s*\\([^)]*\\)\\s*\\{`,
+                    extractName: (match) => this.declaredName(match)
+                }];
 
             case 'cpp':
-                return [
-                    {
-                        regex: `^[a-zA-Z_][a-zA-Z0-9_<>]*\\s+([a-zA-Z_][a-zA-Z0-9_]*)\\s*\\([^)]*\\)\\s*\\{`,
-                        extractName: (match) => match[1] || null
-                    },
-                    {
-                        regex: `^([a-zA-Z_][a-zA-Z0-9_]*)\\s*\\([^)]*\\)\\s*\\{`,
-                        extractName: (match) => match[1] || null
-                    }
-                ];
+                return [{
+                    // Indented members, templates, qualified names, destructors and trailing qualifiers
+                    regex: `^[ \\t]*(?:template\\s*<[^>]*>\\s*)?(?:[a-zA-Z_][a-zA-Z0-9_:<>,]*[ \\t*&]+)*[*&]*((?:[a-zA-Z_][a-zA-Z0-9_]*(?:<[^>]*>)?::)*~?[a-zA-Z_][a-zA-Z0-9_]*)\\s*\\([^)]*\\)\\s*(?:(?:const|noexcept|override|final)\\s*)*\\{`,
+                    extractName: (match) => this.declaredName(match)
+                }];
 
             case 'dart':
                 return [
@@ -286,8 +319,9 @@ export class ReviewCodeLensProvider implements vscode.CodeLensProvider {
 
             case 'java':
                 return [{
-                    regex: `^[a-zA-Z_][a-zA-Z0-9_<>]*\\s+([a-zA-Z_][a-zA-Z0-9_]*)\\s*\\([^)]*\\)\\s*\\{`,
-                    extractName: (match) => match[1] || null
+                    // Indented methods and constructors with modifiers, generics and throws clauses
+                    regex: `^[ \\t]*(?:@[a-zA-Z_][a-zA-Z0-9_.]*(?:\\([^)]*\\))?\\s+)*(?:[a-zA-Z_<][a-zA-Z0-9_<>,.?\\[\\]]*[ \\t]+)*([a-zA-Z_][a-zA-Z0-9_]*)\\s*\\([^)]*\\)\\s*(?:throws\\s+[a-zA-Z0-9_.,\\s]+)?\\{`,
+                    extractName: (match) => this.declaredName(match)
                 }];
             
             default:
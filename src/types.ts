@@ -1,20 +1,65 @@
 export interface Review {
+    id?: string;
     line: number;
     function: string;
     style: string;
     review: string;
     stars: string;
+    column?: number;
+    end_line?: number;
+    end_column?: number;
+    score?: number;
+    sub_scores?: SubScores;
+    rating_source?: 'parsed' | 'inferred' | 'defaulted';
+    fallback?: boolean;
+    error?: string;
+    cached?: boolean;
+    usage?: Usage;
+    status?: 'budget_exhausted';
+    redactions?: Record<string, number>;
+    injection_warnings?: string[];
+    possibly_injected?: boolean;
+}
+
+export interface SubScores {
+    readability?: number;
+    correctness_risk?: number;
+    complexity?: number;
+    error_handling?: number;
+    naming?: number;
+}
+
+export interface Usage {
+    prompt_tokens: number;
+    response_tokens: number;
+    total_tokens: number;
+    cost?: number;
+    estimated?: boolean;
 }
 
 export interface ReviewRequest {
     file_path: string;
     file_content: string;
     style: string;
+    no_cache?: boolean;
+    changed_lines?: number[];
+    anonymize?: boolean;
 }
 
 export interface ReviewResponse {
     file: string;
     reviews: Review[];
+    batch_errors?: BatchError[];
+    partial?: boolean;
+    model?: string;
+    usage?: Usage;
+    budget_exhausted?: boolean;
+}
+
+export interface BatchError {
+    id?: string;
+    function?: string;
+    error: string;
 }
 
 export interface ErrorResponse {
@@ -6,28 +6,169 @@ type ReviewRequest struct {
 	FileContent string `json:"file_content"`
 	Style       string `json:"style"`
 	APIKey      string `json:"api_key,omitempty"`
+	// Generation overrides the model generation parameters for this request
+	Generation *GenerationOptions `json:"generation,omitempty"`
+	// NoCache bypasses the review cache for this request
+	NoCache bool `json:"no_cache,omitempty"`
+	// ChangedLines are the edited lines of the file; functions touching them are
+	// reviewed first when the budget runs short
+	ChangedLines []int `json:"changed_lines,omitempty"`
+	// Anonymize renames identifiers and string literals before code is sent
+	Anonymize bool `json:"anonymize,omitempty"`
+}
+
+// GenerationOptions represents model generation parameters; unset fields keep their defaults
+type GenerationOptions struct {
+	Model             string            `json:"model,omitempty"`
+	Temperature       *float32          `json:"temperature,omitempty"`
+	TopP              *float32          `json:"top_p,omitempty"`
+	MaxOutputTokens   int32             `json:"max_output_tokens,omitempty"`
+	Seed              *int32            `json:"seed,omitempty"`
+	SafetySettings    map[string]string `json:"safety_settings,omitempty"`
+	SystemInstruction string            `json:"system_instruction,omitempty"`
 }
 
 // FunctionInfo represents a detected function in the code
 type FunctionInfo struct {
+	ID       string `json:"id"`
 	Name     string `json:"name"`
 	Line     int    `json:"line"`
 	Language string `json:"language"`
+	// Column is the 1-based character column where the declaration starts
+	Column int `json:"column,omitempty"`
+	// EndLine and EndColumn locate the last character of the function, when the
+	// parser knows them
+	EndLine   int `json:"end_line,omitempty"`
+	EndColumn int `json:"end_column,omitempty"`
+	// Signature is the declaration up to the body, with whitespace collapsed
+	Signature string `json:"signature,omitempty"`
+	// Class is the class, struct or receiver type the function belongs to
+	Class string `json:"class,omitempty"`
+	// QualifiedName is the name prefixed with its class, e.g. "Server.Handle"
+	QualifiedName string `json:"qualified_name,omitempty"`
+	// Params are the parameters as written, e.g. "ctx context.Context"
+	Params []string `json:"params,omitempty"`
+	// Visibility is one of the Visibility* values
+	Visibility string `json:"visibility,omitempty"`
+	// Receiver is the receiver type of a Go method, e.g. "*Server"
+	Receiver string `json:"receiver,omitempty"`
+	// TypeParams are the type parameters of a generic function, e.g. "[T any]"
+	TypeParams string `json:"type_params,omitempty"`
+	// Doc is the doc comment attached to the function
+	Doc string `json:"doc,omitempty"`
 }
 
+// Function visibilities
+const (
+	VisibilityPublic    = "public"
+	VisibilityPrivate   = "private"
+	VisibilityProtected = "protected"
+	// VisibilityPackage is Java's default visibility, within the package only
+	VisibilityPackage = "package"
+)
+
+// Rating sources, telling how the star rating of a review was obtained
+const (
+	// RatingParsed means the reply gave the rating where the prompt asked for it
+	RatingParsed = "parsed"
+	// RatingInferred means the rating was found elsewhere in the reply
+	RatingInferred = "inferred"
+	// RatingDefaulted means the reply had no usable rating and the default was used
+	RatingDefaulted = "defaulted"
+)
+
+// StatusBudgetExhausted marks a function skipped because the spending budget ran out
+const StatusBudgetExhausted = "budget_exhausted"
+
 // Review represents a generated review for a function
 type Review struct {
+	ID       string `json:"id,omitempty"`
 	Line     int    `json:"line"`
 	Function string `json:"function"`
 	Style    string `json:"style"`
 	Review   string `json:"review"`
 	Stars    string `json:"stars"`
+	// Column, EndLine and EndColumn complete the range of the function, when known
+	Column    int `json:"column,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+	EndColumn int `json:"end_column,omitempty"`
+	// Score is the overall rating from 1 to 5, the number of stars in Stars
+	Score int `json:"score,omitempty"`
+	// SubScores are the per-aspect ratings given by the model, when it gave any
+	SubScores *SubScores `json:"sub_scores,omitempty"`
+	// RatingSource is one of the Rating* values
+	RatingSource string `json:"rating_source,omitempty"`
+	// Fallback is set when the provider failed and canned text was used instead
+	Fallback bool `json:"fallback,omitempty"`
+	// Error is the provider error that caused the fallback
+	Error string `json:"error,omitempty"`
+	// Cached is set when the review was served from the on-disk cache
+	Cached bool `json:"cached,omitempty"`
+	// Usage is the share of provider tokens and cost spent on this function
+	Usage *Usage `json:"usage,omitempty"`
+	// Status is set when the function was not reviewed, e.g. StatusBudgetExhausted
+	Status string `json:"status,omitempty"`
+	// Redactions counts the secrets and personal data masked in the code sent, per kind
+	Redactions map[string]int `json:"redactions,omitempty"`
+	// InjectionWarnings describe text in the code that tries to instruct the model
+	// and the ways the reply broke the requested format
+	InjectionWarnings []string `json:"injection_warnings,omitempty"`
+	// PossiblyInjected is set when the reply broke the requested format, which
+	// suggests the model followed instructions found in the code
+	PossiblyInjected bool `json:"possibly_injected,omitempty"`
+}
+
+// SubScores rate single aspects of a function from 1 to 5, zero meaning no
+// score was given. Readability, ErrorHandling and Naming are better when higher;
+// CorrectnessRisk and Complexity are worse when higher.
+type SubScores struct {
+	Readability     int `json:"readability,omitempty"`
+	CorrectnessRisk int `json:"correctness_risk,omitempty"`
+	Complexity      int `json:"complexity,omitempty"`
+	ErrorHandling   int `json:"error_handling,omitempty"`
+	Naming          int `json:"naming,omitempty"`
+}
+
+// Usage counts the tokens sent to and received from a provider
+type Usage struct {
+	PromptTokens   int `json:"prompt_tokens"`
+	ResponseTokens int `json:"response_tokens"`
+	TotalTokens    int `json:"total_tokens"`
+	// Cost is the estimated price in USD, when the model has a known price
+	Cost float64 `json:"cost,omitempty"`
+	// Estimated is set when the backend reported no usage and the counts were estimated
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// Add accumulates another usage into u
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.ResponseTokens += other.ResponseTokens
+	u.TotalTokens += other.TotalTokens
+	u.Cost += other.Cost
+	u.Estimated = u.Estimated || other.Estimated
 }
 
 // ReviewResponse represents the response containing all reviews for a file
 type ReviewResponse struct {
-	File    string   `json:"file"`
-	Reviews []Review `json:"reviews"`
+	File        string       `json:"file"`
+	Reviews     []Review     `json:"reviews"`
+	BatchErrors []BatchError `json:"batch_errors,omitempty"`
+	// Partial is set when the run was cancelled or timed out before every function was reviewed
+	Partial bool `json:"partial,omitempty"`
+	// Model is the model that generated the reviews
+	Model string `json:"model,omitempty"`
+	// Usage is the total provider usage of the run, including calls whose replies were rejected
+	Usage *Usage `json:"usage,omitempty"`
+	// BudgetExhausted is set when functions were skipped because the budget ran out
+	BudgetExhausted bool `json:"budget_exhausted,omitempty"`
+}
+
+// BatchError represents a batch response item that failed validation
+type BatchError struct {
+	ID       string `json:"id,omitempty"`
+	Function string `json:"function,omitempty"`
+	Error    string `json:"error"`
 }
 
 // ErrorResponse represents an error response
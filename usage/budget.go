@@ -0,0 +1,128 @@
+package usage
+
+import (
+	"reviewer-bot/types"
+	"sync"
+	"time"
+)
+
+// Limit caps tokens and cost; a zero field means unlimited
+type Limit struct {
+	Tokens int
+	Cost   float64
+}
+
+// Budget holds the configured spending limits
+type Budget struct {
+	// Run limits a single review run
+	Run Limit
+	// Day limits all runs of the current day
+	Day Limit
+	// Repo limits all runs in the same repository in the current calendar month,
+	// so it starts over on the first of every month; days and months are counted
+	// in local time, as in Summarize
+	Repo Limit
+}
+
+// Enabled reports whether any limit is set
+func (b Budget) Enabled() bool {
+	return b.Run != Limit{} || b.Day != Limit{} || b.Repo != Limit{}
+}
+
+// Tracker returns a tracker holding what is left of the budget for a run in repo,
+// given the runs already recorded in the ledger
+func (b Budget) Tracker(entries []Entry, repo string, now time.Time) *Tracker {
+	var day, repoMonth types.Usage
+	for _, entry := range entries {
+		if dayOf(entry.Time) == dayOf(now) {
+			day.Add(entry.Usage)
+		}
+		if entry.Repo == repo && monthOf(entry.Time) == monthOf(now) {
+			repoMonth.Add(entry.Usage)
+		}
+	}
+
+	tracker := &Tracker{}
+	tracker.limit(b.Run, types.Usage{})
+	tracker.limit(b.Day, day)
+	tracker.limit(b.Repo, repoMonth)
+	return tracker
+}
+
+// Tracker enforces the remaining budget across concurrent calls; a nil tracker
+// allows everything
+type Tracker struct {
+	mu        sync.Mutex
+	tokens    int
+	cost      float64
+	hasTokens bool
+	hasCost   bool
+}
+
+// limit narrows the tracker to what is left of limit after spent
+func (t *Tracker) limit(limit Limit, spent types.Usage) {
+	if limit.Tokens > 0 {
+		remaining := limit.Tokens - spent.TotalTokens
+		if !t.hasTokens || remaining < t.tokens {
+			t.tokens = remaining
+		}
+		t.hasTokens = true
+	}
+	if limit.Cost > 0 {
+		remaining := limit.Cost - spent.Cost
+		if !t.hasCost || remaining < t.cost {
+			t.cost = remaining
+		}
+		t.hasCost = true
+	}
+}
+
+// Fits reports whether the estimated usage fits in what is left, without reserving it
+func (t *Tracker) Fits(estimate types.Usage) bool {
+	if t == nil {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.fits(estimate)
+}
+
+// Reserve takes the estimated usage from the budget, reporting false when it doesn't fit
+func (t *Tracker) Reserve(estimate types.Usage) bool {
+	if t == nil {
+		return true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.fits(estimate) {
+		return false
+	}
+	t.tokens -= estimate.TotalTokens
+	t.cost -= estimate.Cost
+	return true
+}
+
+// Settle replaces a reservation with the usage actually spent
+func (t *Tracker) Settle(reserved, actual types.Usage) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens += reserved.TotalTokens - actual.TotalTokens
+	t.cost += reserved.Cost - actual.Cost
+}
+
+// fits reports whether estimate fits; the caller must hold t.mu
+func (t *Tracker) fits(estimate types.Usage) bool {
+	if t.hasTokens && estimate.TotalTokens > t.tokens {
+		return false
+	}
+	if t.hasCost && estimate.Cost > t.cost {
+		return false
+	}
+	return true
+}
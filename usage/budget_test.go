@@ -0,0 +1,86 @@
+package usage
+
+import (
+	"reviewer-bot/types"
+	"testing"
+	"time"
+)
+
+func tokens(n int) types.Usage {
+	return types.Usage{TotalTokens: n}
+}
+
+func TestTrackerRemaining(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.Local)
+	entries := []Entry{
+		{Time: now.Add(-2 * time.Hour), Repo: "acme/api", Usage: tokens(300)},
+		{Time: now.Add(-72 * time.Hour), Repo: "acme/api", Usage: tokens(500)},
+		{Time: now.AddDate(0, -1, 0), Repo: "acme/api", Usage: tokens(1000)},
+		{Time: now.Add(-time.Hour), Repo: "acme/web", Usage: tokens(200)},
+	}
+
+	tests := []struct {
+		name   string
+		budget Budget
+		now    time.Time
+		want   int
+	}{
+		{"run limit only", Budget{Run: Limit{Tokens: 700}}, now, 700},
+		{"day counts every repo", Budget{Day: Limit{Tokens: 1000}}, now, 500},
+		{"repo counts this month", Budget{Repo: Limit{Tokens: 1000}}, now, 200},
+		{"tightest limit wins", Budget{Run: Limit{Tokens: 5000}, Day: Limit{Tokens: 1000}, Repo: Limit{Tokens: 1000}}, now, 200},
+		{"repo limit starts over each month", Budget{Repo: Limit{Tokens: 1000}}, time.Date(2026, 4, 1, 0, 30, 0, 0, time.Local), 1000},
+		{"spent past the limit", Budget{Repo: Limit{Tokens: 500}}, now, -300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := tt.budget.Tracker(entries, "acme/api", tt.now)
+			if !tracker.Fits(tokens(tt.want)) || tracker.Fits(tokens(tt.want+1)) {
+				t.Errorf("tracker fits up to %d tokens, want exactly %d", tracker.tokens, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackerReserveAndSettle(t *testing.T) {
+	tracker := Budget{Run: Limit{Tokens: 300}}.Tracker(nil, "acme/api", time.Now())
+
+	if !tracker.Reserve(tokens(200)) {
+		t.Fatal("Reserve() refused usage within the limit")
+	}
+	if tracker.Reserve(tokens(200)) {
+		t.Fatal("Reserve() accepted usage past the remaining limit")
+	}
+	// The call spent less than reserved, which frees the difference
+	tracker.Settle(tokens(200), tokens(50))
+	if !tracker.Reserve(tokens(250)) {
+		t.Error("Reserve() refused usage freed by Settle()")
+	}
+	if tracker.Fits(tokens(1)) {
+		t.Error("Fits() accepted usage past an exhausted limit")
+	}
+}
+
+func TestTrackerCost(t *testing.T) {
+	tracker := Budget{Day: Limit{Cost: 1}}.Tracker(nil, "acme/api", time.Now())
+
+	if !tracker.Reserve(types.Usage{TotalTokens: 1_000_000, Cost: 0.6}) {
+		t.Fatal("Reserve() refused usage within the cost limit")
+	}
+	if tracker.Reserve(types.Usage{Cost: 0.6}) {
+		t.Error("Reserve() accepted usage past the cost limit")
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	if (Budget{}).Enabled() {
+		t.Error("an empty budget is enabled")
+	}
+
+	var tracker *Tracker
+	if !tracker.Fits(tokens(1_000_000)) || !tracker.Reserve(tokens(1_000_000)) {
+		t.Error("a nil tracker refused usage")
+	}
+	tracker.Settle(tokens(1), tokens(2))
+}
@@ -0,0 +1,185 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reviewer-bot/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is one review run recorded in the ledger
+type Entry struct {
+	Time  time.Time   `json:"time"`
+	Repo  string      `json:"repo"`
+	File  string      `json:"file"`
+	Model string      `json:"model"`
+	Usage types.Usage `json:"usage"`
+}
+
+// Summary is the spend of one repository in one month
+type Summary struct {
+	Month string      `json:"month"`
+	Repo  string      `json:"repo"`
+	Runs  int         `json:"runs"`
+	Usage types.Usage `json:"usage"`
+}
+
+// Ledger is an append-only JSON lines file of review runs
+type Ledger struct {
+	path string
+}
+
+// DefaultLedgerPath returns the ledger file used when none is configured
+func DefaultLedgerPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "reviewer-bot", "ledger.jsonl")
+}
+
+// NewLedger creates a ledger stored at path
+func NewLedger(path string) *Ledger {
+	if path == "" {
+		path = DefaultLedgerPath()
+	}
+	return &Ledger{path: path}
+}
+
+// Path returns the ledger file path
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append records a review run
+func (l *Ledger) Append(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode ledger entry: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %v", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open ledger: %v", err)
+	}
+	defer file.Close()
+
+	// A single write keeps concurrent runs from interleaving lines
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write ledger: %v", err)
+	}
+	return nil
+}
+
+// Entries reads every recorded run, skipping lines that cannot be parsed
+func (l *Ledger) Entries() ([]Entry, error) {
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %v", err)
+	}
+	return entries, nil
+}
+
+// Summarize groups entries by local month ("2006-01") and repository, keeping
+// only the given month when it is set
+func Summarize(entries []Entry, month string) []Summary {
+	type groupKey struct{ month, repo string }
+	groups := make(map[groupKey]*Summary)
+
+	for _, entry := range entries {
+		entryMonth := monthOf(entry.Time)
+		if month != "" && entryMonth != month {
+			continue
+		}
+
+		key := groupKey{entryMonth, entry.Repo}
+		summary, ok := groups[key]
+		if !ok {
+			summary = &Summary{Month: entryMonth, Repo: entry.Repo}
+			groups[key] = summary
+		}
+		summary.Runs++
+		summary.Usage.Add(entry.Usage)
+	}
+
+	summaries := make([]Summary, 0, len(groups))
+	for _, summary := range groups {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Month != summaries[j].Month {
+			return summaries[i].Month < summaries[j].Month
+		}
+		return summaries[i].Repo < summaries[j].Repo
+	})
+	return summaries
+}
+
+// RepoRoot returns the repository a file belongs to: the nearest parent
+// directory containing .git, or the file's own directory
+func RepoRoot(filePath string) string {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return filepath.Dir(filePath)
+	}
+
+	for dir := filepath.Dir(absPath); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	return filepath.Dir(absPath)
+}
+
+// RepoRelative returns filePath relative to repo, or filePath itself when it is
+// outside the repository
+func RepoRelative(repo, filePath string) string {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return filePath
+	}
+	relative, err := filepath.Rel(repo, absPath)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return filePath
+	}
+	return filepath.ToSlash(relative)
+}
+
+// monthOf returns the local month of t, as "2006-01"
+func monthOf(t time.Time) string {
+	return t.Local().Format("2006-01")
+}
+
+// dayOf returns the local day of t, as "2006-01-02"
+func dayOf(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
@@ -0,0 +1,69 @@
+package usage
+
+import (
+	"reviewer-bot/types"
+	"strings"
+)
+
+// Price is the cost of a model in USD per million tokens
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// PriceTable maps model names, or model name prefixes, to prices
+type PriceTable map[string]Price
+
+// DefaultPrices holds list prices for common hosted models; local models are free.
+// Override or extend them with REVIEWER_PRICES.
+var DefaultPrices = PriceTable{
+	"gemini-2.0-flash-exp":  {Input: 0, Output: 0},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.30},
+	"gemini-2.0-flash":      {Input: 0.10, Output: 0.40},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10.00},
+	"gemini-1.5-flash":      {Input: 0.075, Output: 0.30},
+	"gemini-1.5-pro":        {Input: 1.25, Output: 5.00},
+	"mock":                  {Input: 0, Output: 0},
+}
+
+// NewPriceTable returns the default prices with the given overrides applied
+func NewPriceTable(overrides map[string]Price) PriceTable {
+	table := make(PriceTable, len(DefaultPrices)+len(overrides))
+	for model, price := range DefaultPrices {
+		table[model] = price
+	}
+	for model, price := range overrides {
+		table[model] = price
+	}
+	return table
+}
+
+// Lookup returns the price of a model, matching the longest known prefix so
+// versioned names such as "gemini-2.0-flash-001" resolve
+func (t PriceTable) Lookup(model string) (Price, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	best := ""
+	for name := range t {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t[best], true
+}
+
+// Cost returns the price in USD of the given usage, or 0 for unknown models
+func (t PriceTable) Cost(model string, usage types.Usage) float64 {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.Input + float64(usage.ResponseTokens)*price.Output) / 1e6
+}